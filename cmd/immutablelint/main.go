@@ -8,7 +8,8 @@ import (
 
 	"github.com/frroossst/pls-dont-go/immutablecheck"
 
-	"golang.org/x/tools/go/analysis/singlechecker"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/multichecker"
 )
 
 // These will be set by ldflags during build
@@ -19,32 +20,44 @@ var (
 )
 
 func main() {
-	// quick-and-dirty: if -V or -V=* or --version seen, print version and exit
+	// -V on its own prints our version banner. -V=full is left to the
+	// analysis driver, which answers it in the format go vet -vettool expects.
 	for _, a := range os.Args[1:] {
-		if a == "-V" || strings.HasPrefix(a, "-V=") || a == "--version" {
+		if a == "-V" || a == "--version" {
 			printVersion()
 			os.Exit(0)
 		}
 	}
 
-	// print version anywasy (easier for debugging)
-	fmt.Printf("%s\n", getVersion())
+	// every other option (-log, -loglevel, -format, ...) is an analyzer flag
+	os.Args = qualifyFlags(os.Args, immutablecheck.Analyzer)
+	multichecker.Main(immutablecheck.Analyzer, immutablecheck.AnnotationAnalyzer)
+}
 
-	// Parse the --log flag before singlechecker processes the args
-	var logDest string
-	for i, arg := range os.Args[1:] {
-		if strings.HasPrefix(arg, "--log=") {
-			logDest = strings.TrimPrefix(arg, "--log=")
-			// Remove this flag from os.Args so singlechecker doesn't see it
-			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
-			break
+// qualifyFlags keeps the old unprefixed flag names working now that the
+// binary runs a multichecker: it rewrites each flag of a, such as
+// -engine=ssa, to its -immutablecheck.engine=ssa form.
+func qualifyFlags(args []string, a *analysis.Analyzer) []string {
+	qualified := []string{args[0]}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return append(qualified, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := a.Flags.Lookup(name)
+		if f == nil {
+			qualified = append(qualified, arg)
+			continue
+		}
+		qualified = append(qualified, "-"+a.Name+"."+strings.TrimLeft(arg, "-"))
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && boolFlag.IsBoolFlag()) && i+1 < len(args) {
+			// -engine ssa
+			i++
+			qualified = append(qualified, args[i])
 		}
 	}
-
-	// Set the log destination in the analyzer
-	immutablecheck.SetLogDestination(logDest)
-
-	singlechecker.Main(immutablecheck.Analyzer)
+	return qualified
 }
 
 func printVersion() {
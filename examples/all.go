@@ -1,7 +1,18 @@
 package examples
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -41,8 +52,8 @@ func TestGlobals() {
 
 	GlobalImmutableMap["three"] = 3 // CATCH
 
-	GlobalImmtbl.RecvMutateNum()
-	MutateNum(&GlobalImmtbl)
+	GlobalImmtbl.RecvMutateNum() // CATCH
+	MutateNum(&GlobalImmtbl)     // CATCH
 }
 
 func TestTrueTypeAliases() {
@@ -207,6 +218,30 @@ func (s *Immtbl) RecvMutateMap() {
 	s.Map["recv_catch"] = -77 // CATCH
 }
 
+// forwardMutateNum mutates its argument only through MutateNum
+func forwardMutateNum(s *Immtbl) {
+	MutateNum(s) // CATCH
+}
+
+func forwardReadNum(s *Immtbl) int {
+	return ReadNum(s)
+}
+
+// fillFirst writes through a local alias of s
+func fillFirst(s []int) {
+	t := s
+	t[0] = 1
+}
+
+// NumBumper is implemented by *Immtbl, whose RecvMutateNum writes through
+// its receiver
+type NumBumper interface{ RecvMutateNum() }
+
+// bumpVia mutates whatever b holds through its interface method
+func bumpVia(b NumBumper) {
+	b.RecvMutateNum()
+}
+
 func TestAll() {
 	im := Immtbl{Num: 123, Str: "hello world!", Two: [][]int{{1, 2, 3}, {4, 5, 6}}}
 
@@ -261,22 +296,32 @@ func TestAll() {
 
 	/*
 	 * mutations via functions
-	 * N.B. not caught at call site but inside the function
+	 * caught both at the call site and inside the function
 	 */
-	MutateNum(&im)
-	MutateMap(&im)
+	MutateNum(&im) // CATCH
+	MutateMap(&im) // CATCH
 	_ = ReadNum(&im)
 	_ = ReadStr(&im)
 
 	/*
 	 * mutations via methods
-	 * N.B. not caught at call site but inside the function
+	 * caught both at the call site and inside the function
 	 */
-	im.RecvMutateNum()
-	im.RecvMutateMap()
+	im.RecvMutateNum() // CATCH
+	im.RecvMutateMap() // CATCH
 	_ = im.RecvReadNum()
 	_ = im.RecvReadStr()
 
+	/*
+	 * mutations via method values, method expressions and forwarding
+	 */
+	mutate := im.RecvMutateNum
+	mutate()                     // CATCH
+	(*Immtbl).RecvMutateNum(&im) // CATCH
+	(*Immtbl).RecvReadNum(&im)
+	forwardMutateNum(&im) // CATCH
+	forwardReadNum(&im)
+
 	/*
 	 * mutations via closures
 	 */
@@ -503,8 +548,10 @@ func TestAll() {
 
 	type NumMutator interface{ RecvMutateNum() }
 	var nm NumMutator = &im
-	nm.RecvMutateNum()
+	nm.RecvMutateNum() // CATCH
 
+	fillFirst(im.Arr) // CATCH - t := s aliases the parameter
+	bumpVia(&im)      // CATCH - RecvMutateNum writes through the pointer b holds
 }
 
 func TestMutations() {
@@ -602,3 +649,643 @@ func TestMultipleReturns(imm *Imm, i int) {
 	_, _ = foo, bar
 	foo = "changed" // CATCH - mutating the ImmutableString
 }
+
+func TestLocalTypeIdentityA() {
+	// @immutable
+	type Local int
+
+	var l Local = 1
+	_ = l
+	l = 2 // CATCH
+}
+
+func TestLocalTypeIdentityB() {
+	// same name as the annotated type above, but a distinct type
+	type Local int
+
+	var l Local = 1
+	_ = l
+	l = 2 // this is fine, only the Local in TestLocalTypeIdentityA is immutable
+}
+
+func TestReflect() {
+	im := Immtbl{Arr: []int{1, 2}, Map: map[string]int{}}
+
+	reflect.ValueOf(&im).Elem().Field(0).SetInt(1) // CATCH - chained reflect write
+
+	rv := reflect.ValueOf(&im).Elem()
+	rv.FieldByName("Str").SetString("reflected") // CATCH
+
+	reflect.ValueOf(im.Map).SetMapIndex(reflect.ValueOf("k"), reflect.ValueOf(1)) // CATCH
+
+	arr := reflect.ValueOf(im.Arr)
+	arr.Index(0).Set(reflect.ValueOf(9))         // CATCH
+	reflect.Copy(arr, reflect.ValueOf([]int{7})) // CATCH
+
+	num := reflect.Indirect(reflect.ValueOf(&im)).FieldByName("Num")
+	num.SetInt(3) // CATCH
+
+	rv.FieldByName("Num").SetInt(5) // @allow-mutate
+
+	_ = rv.FieldByName("Num").Int() // reading through reflect is fine
+
+	mutable := cell{}
+	reflect.ValueOf(&mutable).Elem().Field(0).SetInt(1) // this is fine, cell is mutable
+}
+
+func TestUnsafe() {
+	im := Immtbl{Arr: []int{1, 2, 3}}
+
+	base := unsafe.Pointer(&im)
+	num := (*int)(unsafe.Add(base, unsafe.Offsetof(im.Num)))
+	*num = 1 // CATCH - pointer arithmetic into immutable storage
+
+	*(*string)(unsafe.Pointer(&im.Str)) = "reinterpreted" // CATCH
+
+	*(*int)(unsafe.Pointer(uintptr(unsafe.Pointer(&im)) + unsafe.Offsetof(im.Num))) = 2 // CATCH
+
+	elems := unsafe.Slice(&im.Arr[0], len(im.Arr))
+	elems[1] = 9 // CATCH
+
+	data := unsafe.SliceData(im.Arr)
+	*data = 7 // CATCH
+
+	type shadow struct{ Num int }
+	(*shadow)(unsafe.Pointer(&im)).Num = 3 // CATCH - reinterpreting an immutable struct
+
+	ptr := &Immtbl{}
+	r := (*int)(unsafe.Add(unsafe.Pointer(ptr), 8))
+	*r = 1 // CATCH - pointer arithmetic from a pointer to an immutable value
+	x := (*[2]int)(unsafe.Pointer(ptr))
+	x[0] = 3 // CATCH - reinterpreting through a pointer to an immutable value
+
+	local := 0
+	lp := (*int)(unsafe.Pointer(&local))
+	*lp = 1 // this is fine, local is mutable
+}
+
+func TestBuiltins() {
+	im := Immtbl{Arr: []int{1, 2, 3}, Map: map[string]int{"k": 1}}
+	src := []int{9, 9}
+
+	delete(im.Map, "k")          // CATCH
+	clear(im.Map)                // CATCH
+	clear(GlobalImmutableMap)    // CATCH
+	clear(im.Arr)                // CATCH
+	copy(im.Arr, src)            // CATCH
+	copy(im.Arr[1:], src)        // CATCH - re-slice shares the backing array
+	_ = append(im.Arr[:1], 4)    // CATCH - overwrites im.Arr[1]
+	_ = append(im.Arr[:1:2], 4)  // CATCH - room for one more, overwrites im.Arr[1]
+	delete(GlobalImmtbl.Map, "") // @allow-mutate
+
+	_ = append(im.Arr, 4)       // only writes past the end of im.Arr
+	_ = append(im.Arr[:1:1], 4) // full slice expression forces a copy
+	n := len(src) - 1
+	_ = append(im.Arr[:n:n], 4) // so does one with max equal to high
+	copy(src, im.Arr)           // reading from an immutable slice is fine
+	local := map[string]int{"k": 1}
+	delete(local, "k") // this is fine, local is mutable
+
+	s := im.Arr[:1]
+	s = append(s, 5) // CATCH - s shares im.Arr's backing array
+	whole := im.Arr
+	whole = append(whole, 6) // only writes past the end of im.Arr
+	_, _ = s, whole
+}
+
+// @immutable
+type packet struct {
+	Len uint32
+	Buf []byte
+}
+
+func TestStdlibMutators() {
+	im := Immtbl{Arr: []int{3, 1, 2}, Map: map[string]int{"k": 1}}
+	pkt := packet{Buf: make([]byte, 4)}
+	r := bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	other := map[string]int{"x": 1}
+
+	sort.Ints(im.Arr)                                // CATCH
+	slices.Sort(im.Arr)                              // CATCH
+	slices.Reverse(im.Arr[1:])                       // CATCH
+	maps.Copy(im.Map, other)                         // CATCH
+	_ = json.Unmarshal([]byte(`{"Num": 1}`), &im)    // CATCH
+	_ = binary.Read(r, binary.LittleEndian, &im.Num) // CATCH
+	_, _ = fmt.Sscan("7", &im.Num)                   // CATCH
+	_, _ = io.ReadFull(r, pkt.Buf)                   // CATCH
+	_, _ = r.Read(pkt.Buf)                           // CATCH
+
+	sorted := slices.Clone(im.Arr)
+	sort.Ints(sorted)        // this is fine, sorted is a copy
+	maps.Copy(other, im.Map) // reading from an immutable map is fine
+	_ = slices.Contains(im.Arr, 1)
+	var n int
+	_, _ = fmt.Sscan("7", &n) // this is fine, n is mutable
+}
+
+func TestSliceMapAliases() {
+	im := Immtbl{Arr: []int{1, 2, 3}, Two: [][]int{{1, 2}, {3, 4}}, Map: map[string]int{"k": 1}}
+
+	s := im.Arr
+	s[0] = 1 // CATCH - s shares its backing array with im.Arr
+
+	m := im.Map
+	m["x"] = 1 // CATCH
+
+	row := im.Two[0]
+	row[1] = 9 // CATCH
+
+	tail := s[1:]
+	tail[0] = 5 // CATCH - re-slice of an alias
+
+	var first = im.Two[0][:1]
+	first[0] = 0 // CATCH
+
+	for _, r := range im.Two {
+		r[0] = 8 // CATCH
+	}
+
+	for k := range im.Map {
+		delete(m, k) // CATCH
+	}
+
+	sortLater := func() {
+		s[2] = 0 // CATCH - closure capturing an alias
+	}
+	sortLater()
+
+	clear(m)         // CATCH
+	MutateSlice(row) // CATCH
+
+	fresh := make([]int, len(im.Arr))
+	copy(fresh, im.Arr)
+	fresh[0] = 1 // this is fine, fresh is a copy
+
+	for _, n := range im.Arr {
+		n++ // this is fine, n is a copy of an int
+		_ = n
+	}
+}
+
+// MutateSlice writes through its slice parameter
+func MutateSlice(s []int) {
+	s[0] = 1
+}
+
+func TestFlowSensitivity(cond bool) {
+	im := Immtbl{Num: 1, Arr: []int{1, 2}}
+	imPtr := &Immtbl{Num: 2}
+	mapOfImmutables := map[string]Immtbl{"k": im}
+
+	local := 0
+	p := &im.Num
+	p = &local
+	*p = 1 // this is fine, p no longer points into im
+
+	v := mapOfImmutables["k"]
+	v.Num = 1  // this is fine, v is a copy
+	v = *imPtr // CATCH - reassigning an immutable value
+	v.Num = 1  // CATCH - v is no longer a copy taken from the map
+
+	s := []int{0}
+	if cond {
+		s = im.Arr
+	}
+	s[0] = 1 // CATCH - s may alias im.Arr
+
+	w := mapOfImmutables["k"]
+	if cond {
+		w = im // CATCH - reassigning an immutable value
+	}
+	w.Num = 2 // CATCH - w is only a copy on one path
+
+	q := &local
+	for i := 0; i < 2; i++ {
+		*q = i // CATCH - q aliases im.Num from the second iteration on
+		q = &im.Num
+	}
+
+	t := im.Arr
+	t = make([]int, 2)
+	t[0] = 1 // this is fine, t was reassigned to a fresh slice
+}
+
+// Account is mutable, except for the fields annotated individually
+type Account struct {
+	// @immutable
+	ID      string
+	Created int64          `json:"created" immutable:"true"`
+	Owners  []string       // @immutable
+	Limits  map[string]int `immutable:"true"`
+	Balance int
+	Notes   []string
+}
+
+// Audited embeds an identity that must never change
+type Audited struct {
+	// @immutable
+	Account
+	Reviewer string
+}
+
+func TestImmutableFields() {
+	acc := Account{ID: "a1", Owners: []string{"x"}, Limits: map[string]int{}}
+	acc.Balance = 100         // this is fine, Balance is not annotated
+	acc.Notes = nil           // this is fine
+	acc.ID = "a2"             // CATCH - field annotated @immutable
+	acc.Created++             // CATCH - field tagged immutable:"true"
+	acc.Owners[0] = "y"       // CATCH
+	acc.Limits["daily"] = 1   // CATCH
+	delete(acc.Limits, "max") // CATCH
+	sort.Strings(acc.Owners)  // CATCH
+
+	id := &acc.ID
+	*id = "a3" // CATCH - id aliases an immutable field
+
+	owners := acc.Owners
+	owners[0] = "z" // CATCH - owners shares the backing array
+
+	ptr := &acc
+	ptr.ID = "a4"    // CATCH
+	ptr.Balance = 50 // this is fine
+
+	acc = Account{} // this is fine, only the annotated fields are frozen
+
+	aud := Audited{}
+	aud.Reviewer = "r"   // this is fine
+	aud.Account.ID = "x" // CATCH
+	aud.Balance = 1      // CATCH - promoted through the immutable embedded Account
+}
+
+// @immutable
+type Registry struct {
+	Name   string
+	Keys   []string
+	mu     sync.Mutex
+	once   sync.Once
+	lookup *sync.RWMutex
+	hits   atomic.Int64
+	// @mutable
+	cache  map[string]string
+	misses int                 `immutable:"false"`
+	stats  struct{ Loads int } // @mutable
+}
+
+// Guarded embeds its lock
+//
+// @immutable
+type Guarded struct {
+	sync.Mutex
+	Value int
+}
+
+func (r *Registry) Get(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.once.Do(func() {
+		r.cache = map[string]string{} // this is fine, cache is @mutable
+	})
+	if v, ok := r.cache[key]; ok {
+		r.hits.Add(1) // this is fine, atomics are interior mutable
+		return v
+	}
+	r.misses++           // this is fine, tagged immutable:"false"
+	r.stats.Loads++      // this is fine
+	r.cache[key] = key   // this is fine
+	delete(r.cache, "x") // this is fine
+	return key
+}
+
+func (r *Registry) Rename(name string) {
+	r.mu.Lock()
+	r.Name = name // CATCH - only the annotated fields are exempt
+	r.mu.Unlock()
+}
+
+func TestInteriorMutability() {
+	r := &Registry{lookup: &sync.RWMutex{}}
+	_ = r.Get("k") // this is fine, Get only touches mutable fields
+	r.Rename("n")  // CATCH - Rename mutates the receiver
+	r.lookup.RLock()
+	r.lookup.RUnlock()
+	r.Keys[0] = "k" // CATCH
+
+	mu := &r.mu
+	mu.Lock() // this is fine, mu points to a mutable field
+
+	g := Guarded{}
+	g.Lock() // this is fine, promoted through the embedded sync.Mutex
+	g.Unlock()
+	g.Value = 1 // CATCH
+}
+
+// Batch is assembled by its producer and read-only once published
+//
+// @frozen-after
+type Batch struct {
+	Items  []string
+	Count  int
+	sealed bool
+}
+
+func (b *Batch) Add(item string) {
+	b.Items = append(b.Items, item)
+	b.Count++
+}
+
+func (b *Batch) Freeze() {
+	b.sealed = true
+}
+
+// @frozen-after Seal
+type Manifest struct {
+	Files map[string]int
+}
+
+func (m *Manifest) Seal() {}
+
+var lastBatch *Batch
+
+func NewBatch(items ...string) *Batch {
+	b := &Batch{}
+	for _, item := range items {
+		b.Add(item) // this is fine, b has not been published yet
+	}
+	return b
+}
+
+func TestFrozenAfter(cond bool) {
+	b := &Batch{}
+	b.Count = 1 // this is fine, b is still being assembled
+	b.Add("a")
+	ch := make(chan *Batch, 1)
+	ch <- b
+	b.Count = 2 // CATCH - b was sent on a channel
+	b.Add("b")  // CATCH - Add mutates the published receiver
+
+	received := <-ch
+	received.Items[0] = "x" // CATCH - whoever sent it published it
+
+	built := NewBatch("a")
+	built.Count++ // CATCH - returned from its constructor
+
+	g := &Batch{}
+	g.Count = 1 // this is fine
+	lastBatch = g
+	g.Count = 2         // CATCH - stored in a global
+	lastBatch.Count = 3 // CATCH
+
+	f := Batch{}
+	f.Add("a") // this is fine
+	f.Freeze()
+	f.Items = nil // CATCH - frozen by Freeze
+
+	c := &Batch{}
+	if cond {
+		c.Freeze()
+	}
+	c.Count = 1 // CATCH - frozen on one path
+
+	m := &Manifest{Files: map[string]int{}}
+	m.Files["a"] = 1 // this is fine
+	m.Seal()
+	delete(m.Files, "a") // CATCH - frozen by Seal
+
+	for _, batch := range []*Batch{b} {
+		batch.Count = 0 // CATCH - loaded from a slice
+	}
+
+	shared := &Batch{}
+	go func() {
+		ch <- shared
+	}()
+	shared.Count = 1 // CATCH - a goroutine publishes it
+
+	go func() {
+		got := <-ch
+		got.Count = 3 // CATCH
+	}()
+}
+
+// @immutable
+var (
+	MaxRetries = 3
+	Endpoints  = []string{"a", "b"}
+)
+
+var buildTag = "dev" // @immutable
+
+type window struct {
+	Width, Height int
+	Grid          [2]int
+	Title         *string
+}
+
+func compute() window {
+	return window{Width: 1}
+}
+
+func resize(w *window) {
+	w.Width = 0
+}
+
+func TestImmutableVariables(cond bool) {
+	MaxRetries = 5     // CATCH - package-level @immutable variable
+	MaxRetries++       // CATCH
+	Endpoints[0] = "c" // this is fine, only the variable is single-assignment
+	buildTag += "!"    // CATCH
+
+	w := compute() // @immutable
+	w = window{}   // CATCH
+	w.Width = 2    // CATCH - w holds its fields inline
+	w.Grid[1]++    // CATCH
+	*w.Title = "t" // this is fine, the title is not part of w
+
+	p := &w.Height
+	*p = 3            // CATCH - p points into w
+	resize(&w)        // CATCH - resize mutates its parameter w
+	*(&w) = compute() // CATCH
+
+	//@immutable
+	var limit int = 10
+	for limit = range 3 { // CATCH
+	}
+	_ = limit
+
+	n, err := 1, error(nil) // @immutable
+	n, err2 := 2, err       // CATCH - n is reassigned, only err2 is new
+	_, _ = n, err2
+
+	go func() {
+		n = 4 // CATCH - closures may not assign it either
+	}()
+
+	var free = compute()
+	free.Width = 1 // this is fine, free is not annotated
+}
+
+type Theme struct {
+	Name    string
+	Colors  map[string]string
+	Palette []string
+	Font    *Font
+	Size    [2]int
+}
+
+type Font struct {
+	Family string
+}
+
+func setFamily(f *Font, family string) {
+	f.Family = family
+}
+
+func Render( /* @readonly */ theme *Theme, scale int) string {
+	theme.Name = "dark"           // CATCH - theme is read-only
+	theme.Colors["bg"] = "#000"   // CATCH
+	theme.Size[0] *= scale        // CATCH
+	delete(theme.Colors, "fg")    // CATCH
+	setFamily(theme.Font, "mono") // CATCH - setFamily mutates its parameter f
+
+	p := &theme.Size[1]
+	*p = 1 // CATCH - p points into theme
+
+	palette := theme.Palette
+	palette[0] = "red" // CATCH - shares theme's backing array
+
+	scale = 2 // this is fine, scale is not read-only
+	theme = &Theme{}
+	theme.Name = "fresh" // this is fine, theme no longer holds the caller's value
+	return theme.Name
+}
+
+// Describe only reads its theme and font
+//
+// @readonly theme, font
+func Describe(theme Theme, font *Font, out *Font) string {
+	font.Family = "x"       // CATCH
+	theme.Palette[0] = ""   // CATCH - shared with the caller even though theme is a copy
+	theme.Name = "copy"     // CATCH - read-only means read-only
+	out.Family = theme.Name // this is fine, out is not read-only
+	return font.Family
+}
+
+func TestReadonlyParams() {
+	t := &Theme{Colors: map[string]string{}, Font: &Font{}}
+	_ = Render(t, 1)
+	t.Name = "caller" // this is fine, only the callee promised not to write
+	_ = Describe(*t, t.Font, &Font{})
+
+	apply := func( /* @readonly */ f *Font) {
+		f.Family = "serif" // CATCH
+	}
+	apply(t.Font)
+}
+
+type Invoice struct {
+	mu    sync.Mutex
+	Lines []string
+	Total int
+	cache string
+}
+
+// String renders the invoice without changing it
+//
+// @readonly
+func (inv *Invoice) String() string {
+	inv.mu.Lock() // this is fine, the mutex is exempt
+	defer inv.mu.Unlock()
+	inv.cache = fmt.Sprint(inv.Lines) // CATCH - inv is read-only in a @readonly method
+	return inv.summary()
+}
+
+// @readonly
+func (inv *Invoice) summary() string {
+	return fmt.Sprint(inv.Total)
+}
+
+// Validate checks the invoice
+//
+// @readonly
+func (inv *Invoice) Validate() bool {
+	inv.normalize()  // CATCH - normalize is not @readonly
+	inv.recompute()  // CATCH - recompute mutates its receiver
+	_ = inv.String() // this is fine, String is @readonly
+	other := &Invoice{}
+	other.normalize() // this is fine, other is not the receiver
+	return inv.Total >= 0
+}
+
+func (inv *Invoice) normalize() {}
+
+func (inv *Invoice) recompute() {
+	inv.Total = len(inv.Lines)
+}
+
+func TestReadonlyMethods() {
+	inv := &Invoice{}
+	inv.recompute() // this is fine, inv is not read-only here
+	_ = inv.Validate()
+}
+
+var priceCache = map[string]int{}
+
+var priceLog = make(chan string, 8)
+
+var priceTiers = []int{30, 10, 20}
+
+type Basket struct {
+	Items    []int
+	Discount int
+}
+
+// @pure
+func subtotal(items []int) int {
+	total := 0
+	for _, item := range items {
+		total += item // this is fine, total is local
+	}
+	return total
+}
+
+// Price computes the price of a basket
+//
+// @pure
+func Price(b *Basket, code string) int {
+	total := subtotal(b.Items) // this is fine, subtotal is @pure
+	code = strings.ToUpper(code)
+	label := fmt.Sprintf("%s:%d", code, total)
+
+	priceCache[label] = total // CATCH - writes a package-level map
+	lastLabel = label         // CATCH
+	priceLog <- label         // CATCH - channel send
+	println(label)            // CATCH
+	fmt.Println(label)        // CATCH - fmt.Println is not known to be pure
+	b.Discount = 10           // CATCH - b is read-only in a @pure function
+	sort.Ints(b.Items)        // CATCH - sorts the caller's slice
+	local := []int{3, 1}
+	sort.Ints(local)                              // CATCH - sort.Ints is not known to be pure
+	slices.Sort(local)                            // this is fine, slices functions only touch their arguments
+	slices.Sort(priceTiers)                       // CATCH - sorts a package-level slice
+	maps.Copy(priceCache, map[string]int{"x": 1}) // CATCH - writes a package-level map
+	slices.Reverse(b.Items)                       // CATCH - b is read-only in a @pure function
+
+	apply := func(x int) int { return x * 2 }
+	return apply(total) - b.Discount // CATCH - apply is a function value
+}
+
+// @pure
+func (b *Basket) Empty() bool {
+	b.compact() // CATCH - compact is not @readonly
+	return len(b.Items) == 0
+}
+
+func (b *Basket) compact() {}
+
+var lastLabel string
+
+func TestPure() {
+	b := &Basket{Items: []int{1, 2}}
+	_ = Price(b, "x")
+	_ = b.Empty()
+}
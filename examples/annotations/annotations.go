@@ -0,0 +1,141 @@
+// Package annotations collects annotations the annotation analyzer reports
+// as unknown, misspelled, misplaced or redundant. Mentioning @immutable
+// inside prose, as here, is not an annotation at all.
+package annotations
+
+// @imutable // CATCH - misspelled, did you mean @immutable?
+type Misspelled struct{ N int }
+
+// @Immutable // CATCH - keywords are case sensitive
+type Capitalised struct{ N int }
+
+// @immutable-ish // CATCH - not a keyword
+type Suffixed struct{ N int }
+
+// @immutables // CATCH - not a keyword either
+type Plural struct{ N int }
+
+// @deprecated // CATCH - unknown annotation
+type Old struct{}
+
+// @immutable
+// @immutable // CATCH - repeated
+type Twice struct{ N int }
+
+// @immutable
+//
+//pls:immutable // CATCH - repeated in the other spelling
+type Spelled struct{ N int }
+
+// pls:immutable // CATCH - with a space after the slashes it is prose
+type Spaced struct{ N int }
+
+//pls:imutable // CATCH - misspelled, did you mean pls:immutable?
+type Namespaced struct{ N int }
+
+//pls:frobnicate // CATCH - unknown directive
+type Unknown struct{}
+
+//pls:immutable deep // CATCH - types are immutable all the way down already
+type Nested struct{ Items []int }
+
+// Sum is a function, which cannot be immutable
+//
+// @immutable // CATCH - misplaced on a function
+func Sum(a, b int) int {
+	return a + b
+}
+
+// Config holds the service settings
+// @imutable // CATCH - misspelled after a doc line too
+type Config struct{ Port int }
+
+// Settings holds the client settings
+// @Immutable // CATCH - case sensitive after a doc line too
+type Settings struct{ Port int }
+
+// Shift moves a by k
+// @immutable // CATCH - misplaced on a function after a doc line too
+func Shift(a, k int) int {
+	return a + k
+}
+
+// @immutable // CATCH - constants are not variables
+const Limit = 10
+
+type (
+	// @immutable // CATCH - only the doc comment of the whole declaration counts
+	Grouped struct{ N int }
+)
+
+// @pure // CATCH - misplaced on a type
+type Pure struct{}
+
+// @constructor // CATCH - misplaced on a variable
+var Registry = map[string]int{}
+
+// @frozen-after
+type Builder struct {
+	Parts []string
+}
+
+// Frozen is @immutable, which its fields need not repeat
+//
+// @immutable
+type Frozen struct {
+	N    int    // @immutable // CATCH - redundant in an immutable struct
+	Hits int    // @mutable
+	Name string /* @mutable */
+}
+
+// Loose is mutable apart from its ID
+type Loose struct {
+	ID    string // @immutable
+	Count int    // @mutable // CATCH - redundant in a mutable struct
+}
+
+// Scale reads x only
+//
+// @readonly f // CATCH - f is not a parameter
+func Scale(x *Loose, n int) int {
+	return len(x.ID) * n
+}
+
+// @readonly // CATCH - a function cannot be read-only as a whole
+func Plain(x *Loose) int {
+	return x.Count
+}
+
+// Length promises to leave l alone
+//
+// @readonly
+func (l *Loose) Length() int {
+	return len(l.ID)
+}
+
+// Total is pure, so l is read-only already
+//
+// @pure
+// @readonly l // CATCH - redundant on a @pure function
+func Total(l *Loose) int {
+	return l.Count
+}
+
+func Render( /* @readonly */ l *Loose, n /* @pure */ int) int { // CATCH - @pure on a parameter
+	return l.Count + n
+}
+
+func Use() {
+	f := &Frozen{}
+	f.N = 1 // @allow-mutate
+	f.N = 2 //pls:allow-mutate reason="set up for the test"
+	f.N = 3 //pls:allow-mutate reson="typo" // CATCH - unknown option
+	l := Loose{}
+	l.Count = 2 // @allow-mutate // CATCH - nothing to suppress
+
+	window := 3 // @immutable
+	_ = window
+	total := 1
+	total++ // @immutable // CATCH - not a declaration
+	_ = total
+}
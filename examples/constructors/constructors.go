@@ -0,0 +1,132 @@
+// Package constructors is checked with -constructor-pattern=^New: functions
+// named New... and functions annotated @constructor may initialise the
+// immutable values they allocate until those values escape.
+package constructors
+
+// @immutable
+type Config struct {
+	Name  string
+	Port  int
+	Tags  map[string]string
+	Hosts []string
+	Inner *Limits
+}
+
+type Limits struct {
+	Max int
+}
+
+var registry []*Config
+
+var published = make(chan *Config, 1)
+
+func NewConfig(name string, port int) *Config {
+	c := &Config{}
+	c.Name = name // this is fine, c is still under construction
+	c.Port = port
+	c.Port++
+	c.Tags = map[string]string{}
+	c.Tags["env"] = "dev"
+	c.Hosts = append(c.Hosts, "localhost")
+	c.Inner = &Limits{}
+	c.Inner.Max = 10
+	delete(c.Tags, "env")
+	c.applyDefaults()
+	if c.Port > 0 && c != nil {
+		c.Name += "!"
+	}
+	return c
+}
+
+func NewRegistered(name string) *Config {
+	c := &Config{Name: name}
+	registry = append(registry, c)
+	c.Port = 80 // CATCH - c escaped into registry
+	return c
+}
+
+func NewPublished() *Config {
+	c := new(Config)
+	c.Port = 1 // this is fine
+	published <- c
+	c.Port = 2 // CATCH - c was sent on a channel
+	return c
+}
+
+func NewFromBase(base *Config) *Config {
+	base.Port = 1 // CATCH - base was not allocated here
+	c := *base
+	c.Port = 2 // CATCH - c is a copy of a value allocated elsewhere
+	return &c
+}
+
+func NewConditional(cond bool) *Config {
+	c := &Config{}
+	if cond {
+		registry = append(registry, c)
+	}
+	c.Port = 1 // CATCH - c escaped on one path
+	return c
+}
+
+func NewValue() Config {
+	var c Config
+	c.Name = "zero" // this is fine
+	c = Config{Name: "literal"}
+	c.Port = 1 // this is fine
+	return c
+}
+
+// build assembles a Config without following the naming pattern
+//
+// @constructor
+func build(name string) *Config {
+	c := &Config{}
+	c.Name = name // this is fine
+	hosts := c.Hosts
+	hosts = append(hosts, "x")
+	c.Hosts = hosts // CATCH - c escaped when its Hosts were shared
+	return c
+}
+
+func NewSelfRegistered() *Config {
+	c := &Config{}
+	c.Register()
+	c.Port = 8080 // CATCH - Register stored c in the registry
+	return c
+}
+
+func NewNormalized() *Config {
+	c := &Config{}
+	c.normalize()
+	c.Port = 8080 // this is fine, normalize keeps c to itself
+	return c
+}
+
+func configure(name string) *Config {
+	c := &Config{}
+	c.Name = name // CATCH - configure is not a constructor
+	return c
+}
+
+func (c *Config) applyDefaults() {
+	c.Port = 8080 // CATCH - the receiver comes from outside
+}
+
+func (c *Config) normalize() {
+	if c.Port == 0 {
+		c.applyDefaults() // CATCH - the receiver comes from outside
+		c.normalize()     // CATCH
+	}
+}
+
+func (c *Config) Register() {
+	registry = append(registry, c)
+}
+
+func TestConstructed() {
+	c := NewConfig("svc", 1)
+	c.Port = 2 // CATCH
+	_ = build("x")
+	_ = configure("y")
+}
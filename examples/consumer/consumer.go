@@ -0,0 +1,99 @@
+package consumer
+
+import "github.com/frroossst/pls-dont-go/examples/domain"
+
+func TestImportedStruct() {
+	cfg := domain.NewConfig("svc")
+	cfg.Port = 9090        // CATCH - immutable type declared in another package
+	cfg.Ports[0] = 1       // CATCH
+	cfg.Tags["env"] = "qa" // CATCH
+	cfg.Port++             // CATCH
+
+	domain.Default.Name = "changed" // CATCH - imported global of immutable type
+
+	var c domain.Config
+	c = domain.Config{} // CATCH
+	_ = c
+}
+
+func TestImportedNamedTypes() {
+	var id domain.ID = "abc"
+	_ = id
+	id = "def" // CATCH
+
+	var labels domain.Labels = domain.Labels{"a": "b"}
+	labels["c"] = "d" // CATCH - alias declared @immutable in another package
+}
+
+func TestImportedMutators() {
+	cfg := domain.NewConfig("svc")
+	domain.Rename(cfg, "other") // CATCH - Rename mutates its parameter, known from facts
+	cfg.SetPort(1)              // CATCH
+	_ = cfg.Address()
+}
+
+func TestImportedImmutableField() {
+	s := domain.Session{ID: "s1"}
+	s.Active = true // this is fine
+	s.ID = "s2"     // CATCH - field tagged immutable in another package
+}
+
+func TestImportedFrozenAfter() {
+	r := &domain.Report{}
+	r.Lines = append(r.Lines, "ok") // this is fine, r is still being filled in
+	r.Publish()
+	r.Lines = nil // CATCH - frozen by Publish, known from facts
+}
+
+func TestImportedImmutableVariable() {
+	domain.DefaultPort = 80 // CATCH - package-level variable annotated in another package
+	port := domain.DefaultPort
+	port++ // this is fine, port is a local copy
+	_ = port
+
+	domain.DefaultHosts[0] = "example.com" // CATCH - deep, known from facts
+	hosts := append([]string(nil), domain.DefaultHosts...)
+	hosts[0] = "example.com" // this is fine, hosts is a copy
+}
+
+// Page embeds a document from another package
+type Page struct {
+	*domain.Document
+}
+
+// Render only reads the page
+//
+// @readonly
+func (p *Page) Render() string {
+	p.Touch() // CATCH - Touch is not @readonly, known from facts
+	return p.Heading()
+}
+
+// Length totals the words of a document
+//
+// @pure
+func Length(lines []string) int {
+	total := 0
+	for _, line := range lines {
+		total += domain.Words(line) // this is fine, Words is @pure, known from facts
+	}
+	domain.NewConfig("length") // CATCH - NewConfig is not known to be pure
+	return total
+}
+
+func TestImportedMutable() {
+	s := domain.Settings{}
+	s.Verbose = true // this is fine, Settings is not @immutable
+}
+
+// Config shares its name with domain.Config but is a distinct, mutable type
+type Config struct {
+	Port int
+}
+
+func TestSameNameDifferentPackage() {
+	c := Config{}
+	c.Port = 1 // this is fine, only domain.Config is @immutable
+	c = Config{}
+	_ = c
+}
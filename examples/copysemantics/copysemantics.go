@@ -0,0 +1,126 @@
+// Package copysemantics is checked with -copy-semantics: writes to private
+// value copies of immutable values are allowed unless they reach storage the
+// copy still shares with the original.
+package copysemantics
+
+import "sort"
+
+// @immutable
+type Point struct {
+	X, Y  int
+	Inner inner
+	Grid  [2]int
+	Tags  []string
+	Attrs map[string]string
+	Next  *inner
+}
+
+type inner struct {
+	Value int
+}
+
+var Origin = Point{Tags: []string{"origin"}, Attrs: map[string]string{}}
+
+func NewPoint() *Point {
+	return &Point{Tags: []string{}, Attrs: map[string]string{}, Next: &inner{}}
+}
+
+func TestPlainCopies() {
+	p := Point{}
+	p.X = 1 // CATCH - p is the original, not a copy
+
+	c := p
+	c.X = 1            // this is fine, c is a private copy
+	c.Inner.Value++    // this is fine, Inner is held inline
+	c.Grid[0] = 1      // this is fine, arrays are held inline
+	c.Tags[0] = "x"    // CATCH - the backing array is shared with p
+	c.Attrs["k"] = "v" // CATCH - the map is shared with p
+	c.Next.Value = 1   // CATCH - the pointee is shared with p
+	c = Point{}        // this is fine, replacing a copy
+	c.X = 2            // CATCH - c now holds a fresh value, no longer a copy
+
+	g := Origin
+	g.Y = 1                          // this is fine
+	g.Tags = append(g.Tags, "moved") // this is fine, the field is the copy's own
+	clear(g.Attrs)                   // CATCH
+}
+
+func TestPointerCopies() {
+	ptr := NewPoint()
+	c := *ptr
+	c.X = 1   // this is fine
+	ptr.X = 1 // CATCH
+
+	var d Point = *ptr
+	d.Inner = inner{} // this is fine
+	d.Tags[0] = "x"   // CATCH
+}
+
+func TestRangeCopies(points []Point) {
+	for _, p := range points {
+		p.X = 1         // this is fine, p is a copy of the element
+		p.Tags[0] = "x" // CATCH
+	}
+	for i := range points {
+		points[i].X = 1 // CATCH - writes the element itself
+	}
+}
+
+func TestConditionalCopy(cond bool) {
+	p := Point{}
+	c := p
+	if cond {
+		c = Point{}
+	}
+	c.X = 1 // CATCH - not a copy on every path
+}
+
+func (p Point) Moved(dx int) Point {
+	p.X += dx // this is fine, p is a value receiver
+	return p
+}
+
+func (p Point) Tag(tag string) {
+	p.Tags[0] = tag // CATCH - shared with the caller's value
+}
+
+func (p *Point) Reset() {
+	p.X = 0 // CATCH - pointer receiver
+}
+
+func Shift(p Point) Point {
+	p.Y++ // this is fine, p is passed by value
+	return p
+}
+
+func TestCalls() {
+	p := Point{}
+	c := p
+	c.Reset()      // this is fine, resets the copy
+	p.Reset()      // CATCH
+	fill(&c.Inner) // this is fine
+	fill(p.Next)   // CATCH
+}
+
+func fill(in *inner) {
+	in.Value = 1
+}
+
+func TestCallsThroughShared() {
+	p := Point{}
+	c := p
+	sort.Strings(c.Tags) // CATCH - sorts the backing array shared with p
+	setFirst(c.Tags)     // CATCH
+	put(c.Attrs)         // CATCH - the map is shared with p
+	fill(c.Next)         // CATCH - the pointee is shared with p
+	c.Tag("x")           // CATCH - the value receiver still shares Tags
+	fill(&c.Inner)       // this is fine, Inner is held inline
+}
+
+func setFirst(tags []string) {
+	tags[0] = "first"
+}
+
+func put(attrs map[string]string) {
+	attrs["k"] = "v"
+}
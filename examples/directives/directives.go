@@ -0,0 +1,112 @@
+// Package directives spells its annotations as Go-style directives, which
+// gofmt leaves alone and go/ast keeps out of doc text
+package directives
+
+import "sort"
+
+// Point is immutable
+//
+//pls:immutable
+type Point struct {
+	X, Y int
+}
+
+// Account is mutable apart from its ID
+type Account struct {
+	ID      string //pls:immutable
+	Balance int
+}
+
+// Cache is immutable apart from its hit counter
+//
+//pls:immutable
+type Cache struct {
+	Entries map[string]string
+	Hits    int //pls:mutable
+}
+
+func TestTypes() {
+	p := Point{}
+	p.X = 1 // CATCH
+
+	a := Account{}
+	a.ID = "x"    // CATCH
+	a.Balance = 1 // this is fine, Balance is not immutable
+	a.ID = "y"    //pls:allow-mutate reason="ids are rewritten during the migration"
+
+	c := &Cache{}
+	c.Hits++             // this is fine, Hits is mutable
+	c.Entries["k"] = "v" // CATCH
+	c.Entries["k"] = "w" //pls:allow-mutate
+	c.Entries["k"] = "x" // @allow-mutate reason="the @-form takes options too"
+	c.Entries = nil      // CATCH
+}
+
+// Grid refers to its cells and to its owner
+type Grid struct {
+	Cells []int
+	Owner *Account
+	Size  int
+}
+
+// Shallow may not be assigned, but what it refers to may be changed
+//
+//pls:immutable
+var Shallow = Grid{Cells: []int{1, 2}, Owner: &Account{}}
+
+// Deep may not be changed through at all
+//
+//pls:immutable deep
+var Deep = Grid{Cells: []int{1, 2}, Owner: &Account{}}
+
+func TestDeep() {
+	Shallow.Size = 1          // CATCH
+	Shallow.Cells[0] = 1      // this is fine, the elements are not part of the variable
+	Shallow.Owner.Balance = 1 // this is fine, neither is the owner
+
+	Deep.Size = 1             // CATCH
+	Deep.Cells[0] = 1         // CATCH - the elements are frozen too
+	Deep.Owner.Balance = 1    // CATCH - and so is the owner
+	sort.Ints(Deep.Cells)     // CATCH
+	sort.Ints(Deep.Cells[1:]) // CATCH
+
+	cells := Deep.Cells
+	cells[1] = 2 // CATCH - cells shares the elements of Deep
+	owner := Deep.Owner
+	owner.Balance++ // CATCH
+	p := &Deep
+	p.Cells[0] = 3 // CATCH
+
+	copied := append([]int(nil), Deep.Cells...)
+	copied[0] = 1 // this is fine, copied is a copy
+
+	totals := map[string]int{"a": 1} //pls:immutable deep
+	totals["a"] = 2                  // CATCH
+	counts := map[string]int{"a": 1} //pls:immutable
+	counts["a"] = 2                  // this is fine, only counts itself is frozen
+	counts = nil                     // CATCH
+	_ = counts
+}
+
+// Sum reads the grid only
+//
+//pls:readonly g
+func Sum(g *Grid) int {
+	g.Size = 0 // CATCH
+	total := 0
+	for _, c := range g.Cells {
+		total += c
+	}
+	return total
+}
+
+// Max has no side effects
+//
+//pls:pure
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	Shallow.Size = b // CATCH - writes a package-level variable, and Shallow
+	return b
+}
@@ -0,0 +1,104 @@
+package domain
+
+// @immutable
+type Config struct {
+	Name  string
+	Port  int
+	Ports []int
+	Tags  map[string]string
+}
+
+// @immutable
+type ID string
+
+// @immutable
+type Labels = map[string]string
+
+// Settings is not annotated and may be freely mutated by importers
+type Settings struct {
+	Verbose bool
+}
+
+// DefaultPort is fixed at initialisation
+//
+// @immutable
+var DefaultPort = 8080
+
+// DefaultHosts lists the hosts every service trusts, down to its elements
+//
+//pls:immutable deep
+var DefaultHosts = []string{"localhost"}
+
+var Default = Config{Name: "default", Port: 8080, Tags: map[string]string{}}
+
+func NewConfig(name string) *Config {
+	return &Config{Name: name, Tags: map[string]string{}}
+}
+
+// Rename mutates the Config it is given
+func Rename(c *Config, name string) {
+	c.Name = name // CATCH
+}
+
+func (c *Config) SetPort(port int) {
+	c.Port = port // CATCH
+}
+
+func (c *Config) Address() string {
+	return c.Name
+}
+
+// Session is mutable apart from its identifier
+type Session struct {
+	ID     string `immutable:"true"`
+	Active bool
+}
+
+// Report is filled in by its producer and frozen once it calls Publish
+//
+// @frozen-after Publish
+type Report struct {
+	Lines []string
+}
+
+func (r *Report) Publish() {}
+
+// Document is mutable, but some of its methods promise not to change it
+type Document struct {
+	Title   string
+	Version int
+}
+
+// Heading returns the title
+//
+// @readonly
+func (d *Document) Heading() string {
+	return d.Title
+}
+
+// Touch marks the document as changed
+func (d *Document) Touch() {}
+
+// Words counts the words of a line
+//
+// @pure
+func Words(line string) int {
+	count := 0
+	inWord := false
+	for _, r := range line {
+		if r == ' ' {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			count++
+		}
+	}
+	return count
+}
+
+// Describe formats a configuration without changing it
+//
+// @readonly c
+func Describe(c *Config) string {
+	return c.Name
+}
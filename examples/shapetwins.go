@@ -0,0 +1,70 @@
+package examples
+
+// Regression corpus: types that only share the underlying structure of
+// an @immutable type must not be reported. Immutability follows declared
+// identity and true type alias chains only.
+
+// @immutable
+type Vec struct {
+	X int
+}
+
+// Point has the same underlying structure as Vec but is a distinct type
+type Point struct {
+	X int
+}
+
+// VecRef is a plain alias, so it denotes Vec itself
+type VecRef = Vec
+
+// @immutable
+type FrozenPoint = Point
+
+// PointRef is an alias chain that passes through FrozenPoint
+type PointRef = FrozenPoint
+
+// @immutable
+type FrozenInts = []int
+
+// MyInts shares the underlying type of FrozenInts but is a distinct type
+type MyInts []int
+
+// @immutable
+type Celsius float64
+
+type Fahrenheit float64
+
+func TestShapeTwins() {
+	v := Vec{X: 1}
+	v.X = 2 // CATCH
+
+	p := Point{X: 1}
+	p.X = 2 // OK - Point only shares Vec's shape
+
+	var vr VecRef = VecRef{X: 1}
+	vr.X = 2 // CATCH - alias of Vec
+
+	var fp FrozenPoint = FrozenPoint{X: 1}
+	fp.X = 2 // CATCH - spelled through the @immutable alias
+
+	var pr PointRef = PointRef{X: 1}
+	pr.X = 2 // CATCH - alias chain passes through FrozenPoint
+
+	var fi FrozenInts = FrozenInts{1, 2}
+	fi[0] = 3 // CATCH
+
+	mi := MyInts{1, 2}
+	mi[0] = 3 // OK - MyInts only shares the shape of FrozenInts
+
+	plain := []int{1, 2}
+	plain[0] = 3 // OK - []int is only immutable when spelled through FrozenInts
+
+	var c Celsius = 1
+	_ = c
+	c = 2 // CATCH
+
+	var f Fahrenheit = 1
+	_ = f
+	f = 2 // OK - Fahrenheit only shares the shape of Celsius
+	_ = f
+}
@@ -0,0 +1,141 @@
+// Package strict is checked with -strict-readonly: references into immutable
+// or read-only storage may only flow into parameters, fields and variables
+// that are read-only themselves.
+package strict
+
+import (
+	"fmt"
+
+	"github.com/frroossst/pls-dont-go/examples/domain"
+)
+
+// @immutable
+type Shape struct {
+	Sides  int
+	Points []int
+	Cache  *Stats // @mutable
+}
+
+type Stats struct {
+	Hits int
+}
+
+// Canvas is mutable, apart from the shape it was given
+type Canvas struct {
+	Shape   *Shape
+	Pinned  *Shape // @immutable
+	Corners []int
+}
+
+var current *Shape
+
+// area only reads the shape it is given
+//
+// @readonly s
+func area(s *Shape) int {
+	return s.Sides
+}
+
+// perimeter does not write to s either, but does not promise so
+func perimeter(s *Shape) int {
+	return s.Sides * 2
+}
+
+// grow writes through s and is reported by the mutating call checks
+func grow(s *Shape) {
+	s.Sides++ // CATCH
+}
+
+// count ignores its argument
+func count(_ *Shape) int {
+	return 1
+}
+
+// Describe only reads the shape
+//
+// @readonly
+func (s *Shape) Describe() string {
+	return fmt.Sprint(s.Sides)
+}
+
+// Corners does not promise to leave the shape alone
+func (s *Shape) Corners() []int {
+	return s.Points
+}
+
+func (s Shape) Copy() Shape {
+	return s
+}
+
+// @pure
+func total(points []int) int {
+	sum := 0
+	for _, p := range points {
+		sum += p
+	}
+	return sum
+}
+
+func TestParameters() {
+	sh := Shape{Sides: 3, Points: []int{1, 2, 3}}
+
+	_ = area(&sh)        // this is fine, s is @readonly
+	_ = perimeter(&sh)   // CATCH - &sh passed to perimeter parameter s, which is not read-only
+	grow(&sh)            // CATCH - reported once, as a mutating call
+	_ = count(&sh)       // this is fine, the parameter is unnamed
+	_ = total(sh.Points) // this is fine, the parameters of a @pure function are read-only
+	_ = sh.Describe()    // this is fine, Describe is @readonly
+	_ = sh.Corners()     // CATCH - &sh passed as the receiver of Corners
+	_ = sh.Copy()        // this is fine, a value receiver gets a copy
+	fmt.Println(&sh)     // CATCH - fmt.Println does not promise anything
+
+	p := &sh                // this is fine, local variables are alias tracked
+	_ = perimeter(p)        // CATCH
+	_ = perimeter(p)        //@allow-mutate
+	_ = (*Shape).Corners(p) // CATCH - method expression receiver
+}
+
+// render reads the canvas but hands its shape on
+//
+// @readonly c
+func render(c *Canvas) int {
+	return perimeter(c.Shape) // CATCH - c.Shape is read-only through c
+}
+
+func TestStores() {
+	sh := &Shape{Sides: 4}
+
+	current = sh // CATCH - package-level variables are not read-only
+
+	var cv Canvas
+	cv.Shape = sh          // CATCH
+	cv.Pinned = sh         // CATCH - assigning the @immutable field itself, not a flow
+	cv.Corners = sh.Points // CATCH - the points belong to the shape
+
+	_ = Canvas{Shape: sh}  // CATCH
+	_ = Canvas{Pinned: sh} // this is fine, Pinned is @immutable
+	_ = &Canvas{
+		nil,
+		sh,        // this is fine, positional Pinned
+		sh.Points, // CATCH
+	}
+	_ = &Canvas{ // @allow-mutate
+		Shape: sh, // this is fine, the directive is on the line of the literal
+	}
+	_ = &Canvas{
+		Shape: sh, // @allow-mutate // CATCH - the directive belongs on the line of the literal
+	}
+
+	stats := &Stats{}
+	_ = Shape{Cache: stats} // this is fine, stats is not read-only
+	_ = Canvas{Shape: &Shape{}}
+	_ = render(&cv)
+}
+
+func TestImported() {
+	cfg := domain.NewConfig("strict")
+
+	_ = domain.Describe(cfg) // this is fine, c is @readonly, known from facts
+	_ = cfg.Address()        // CATCH - Address is not @readonly
+	domain.Rename(cfg, "x")  // CATCH - reported once, as a mutating call
+}
@@ -0,0 +1,35 @@
+// Package unsafepointer is checked with -severity=unsafe-pointer=error: every
+// unsafe.Pointer taken from immutable storage is reported, even when nothing
+// is written through it.
+package unsafepointer
+
+import "unsafe"
+
+// @immutable
+type Header struct {
+	Num  int
+	Name string
+	Data []byte
+}
+
+type scratch struct {
+	Num int
+}
+
+func TestPointers() {
+	h := Header{Num: 1, Name: "h", Data: []byte{1, 2}}
+
+	_ = unsafe.Pointer(&h.Num)                                                // CATCH
+	_ = unsafe.Pointer(&h)                                                    // CATCH
+	_ = (*int)(unsafe.Pointer(&h.Num))                                        // CATCH
+	_ = uintptr(unsafe.Pointer(&h.Data[0]))                                   // CATCH
+	_ = unsafe.Pointer(unsafe.StringData(h.Name))                             // CATCH
+	_ = unsafe.Pointer(uintptr(unsafe.Pointer(&h)) + unsafe.Offsetof(h.Name)) // CATCH - reported once, for the innermost conversion
+
+	ptr := &h
+	_ = unsafe.Pointer(ptr) // CATCH
+
+	s := scratch{}
+	_ = unsafe.Pointer(&s.Num) // this is fine, scratch is mutable
+	_ = unsafe.Sizeof(h)       // this is fine, nothing is taken from h
+}
@@ -0,0 +1,421 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// AnnotationAnalyzer validates the annotations read by Analyzer
+var AnnotationAnalyzer = NewAnnotationAnalyzer(Analyzer)
+
+// NewAnnotationAnalyzer returns the companion analyzer of checker, an
+// analyzer returned by NewAnalyzer. It parses every comment starting with @
+// or with the //pls: namespace as a directive and reports the unknown,
+// misspelled, misplaced and redundant ones, using the keywords checker was
+// configured with.
+func NewAnnotationAnalyzer(checker *analysis.Analyzer) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:     "annotationcheck",
+		Doc:      "report unknown, misspelled, misplaced and redundant immutablecheck annotations",
+		Requires: []*analysis.Analyzer{checker},
+		Run: func(pass *analysis.Pass) (any, error) {
+			result, ok := pass.ResultOf[checker].(*checkResult)
+			if !ok {
+				return nil, fmt.Errorf("annotationcheck: %s is not an immutablecheck analyzer", checker.Name)
+			}
+			checkAnnotations(pass, result)
+			return nil, nil
+		},
+	}
+}
+
+// NewAnalyzers returns the analyzer bound to settings and its companion
+// annotation analyzer
+func NewAnalyzers(settings Settings) []*analysis.Analyzer {
+	checker := NewAnalyzer(settings)
+	return []*analysis.Analyzer{checker, NewAnnotationAnalyzer(checker)}
+}
+
+// annotation categories, used as diagnostic categories of the annotation analyzer
+const (
+	annotationUnknown    = "unknown"
+	annotationMisspelled = "misspelled"
+	annotationMisplaced  = "misplaced"
+	annotationRedundant  = "redundant"
+)
+
+// placements of a directive comment, phrased for diagnostics
+const (
+	placeType      = "a type declaration"
+	placeTypeSpec  = "a type inside a grouped declaration"
+	placeVar       = "a variable declaration"
+	placeConst     = "a constant declaration"
+	placeDefine    = "a short variable declaration"
+	placeField     = "a struct field"
+	placeFunc      = "a function"
+	placeMethod    = "a method"
+	placeParam     = "a parameter"
+	placeStatement = "this line"
+)
+
+// directivePlacements lists where each kind of directive takes effect, and
+// how to say so. Allow-mutate directives may appear on any line; whether
+// they suppress anything is checked separately.
+var directivePlacements = map[string]struct {
+	places []string
+	where  string
+}{
+	directiveImmutable:   {[]string{placeType, placeVar, placeDefine, placeField}, "type and variable declarations, short variable declarations and struct fields"},
+	directiveMutable:     {[]string{placeField}, "fields of @immutable structs"},
+	directiveConstructor: {[]string{placeFunc, placeMethod}, "function declarations"},
+	directiveReadonly:    {[]string{placeFunc, placeMethod, placeParam}, "parameters, in a function's doc comment or inline, and methods"},
+	directivePure:        {[]string{placeFunc, placeMethod}, "function declarations"},
+	directiveFrozenAfter: {[]string{placeType}, "type declarations"},
+}
+
+// knownDirectives maps every configured keyword, and the namespaced name of
+// every kind, to its directive kind
+func knownDirectives(settings *Settings) map[string]string {
+	known := make(map[string]string)
+	for kind, keywords := range map[string][]string{
+		directiveImmutable:   settings.ImmutableKeywords,
+		directiveMutable:     settings.MutableKeywords,
+		directiveConstructor: settings.ConstructorKeywords,
+		directiveReadonly:    settings.ReadonlyKeywords,
+		directivePure:        settings.PureKeywords,
+		directiveFrozenAfter: settings.FrozenAfterKeywords,
+		directiveAllowMutate: settings.AllowMutateKeywords,
+	} {
+		for _, keyword := range keywords {
+			known[keyword] = kind
+		}
+		known[directivePrefix+kind] = kind
+	}
+	return known
+}
+
+// annotationChecker validates the directives of one file
+type annotationChecker struct {
+	pass       *analysis.Pass
+	result     *checkResult
+	known      map[string]string
+	placements map[*ast.Comment]string
+	params     [][2]token.Pos        // parameter lists of function declarations and literals
+	defines    map[int]bool          // lines holding a short variable declaration
+	fields     map[*ast.Comment]bool // field comments, mapped to whether their struct is immutable
+	funcs      map[*ast.Comment]*ast.FuncDecl
+}
+
+func checkAnnotations(pass *analysis.Pass, result *checkResult) {
+	known := knownDirectives(result.settings)
+	for _, file := range pass.Files {
+		c := &annotationChecker{
+			pass:       pass,
+			result:     result,
+			known:      known,
+			placements: make(map[*ast.Comment]string),
+			defines:    make(map[int]bool),
+			fields:     make(map[*ast.Comment]bool),
+			funcs:      make(map[*ast.Comment]*ast.FuncDecl),
+		}
+		c.collectPlacements(file)
+		skipRedundant := result.suppressed == nil || (result.settings.ExemptTests && isTestFile(pass, file))
+		for _, group := range file.Comments {
+			seen := make(map[string]bool)
+			for _, comment := range group.List {
+				d, ok := parseDirective(comment)
+				if !ok {
+					continue
+				}
+				kind, ok := known[d.name]
+				switch {
+				case ok && !d.namespaced && strings.HasPrefix(d.name, directivePrefix):
+					c.report(d, annotationMisspelled, "%s is not a directive with a space after the comment marker, write //%s", d.name, d.name)
+					continue
+				case !ok && (d.namespaced || strings.HasPrefix(d.name, "@")):
+					c.reportUnknown(d)
+					continue
+				case !ok:
+					continue
+				}
+				if seen[kind] {
+					c.report(d, annotationRedundant, "%s is repeated in the same comment", d.name)
+					continue
+				}
+				seen[kind] = true
+				c.checkDirective(d, kind, skipRedundant)
+			}
+		}
+	}
+}
+
+// collectPlacements records where each comment of file sits: which
+// declaration it documents, or which parameter list or line it is part of
+func (c *annotationChecker) collectPlacements(file *ast.File) {
+	place := func(group *ast.CommentGroup, placement string) {
+		if group == nil {
+			return
+		}
+		for _, comment := range group.List {
+			c.placements[comment] = placement
+		}
+	}
+	fieldList := func(list *ast.FieldList) {
+		if list != nil && list.Opening.IsValid() {
+			c.params = append(c.params, [2]token.Pos{list.Opening, list.Closing})
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.GenDecl:
+			switch node.Tok {
+			case token.TYPE:
+				place(node.Doc, placeType)
+				for _, spec := range node.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					place(typeSpec.Doc, placeTypeSpec)
+					place(typeSpec.Comment, placeTypeSpec)
+					if st, ok := typeSpec.Type.(*ast.StructType); ok {
+						c.placeFields(st, c.isImmutableTypeSpec(typeSpec))
+					}
+				}
+			case token.VAR:
+				place(node.Doc, placeVar)
+				for _, spec := range node.Specs {
+					place(spec.(*ast.ValueSpec).Doc, placeVar)
+					place(spec.(*ast.ValueSpec).Comment, placeVar)
+				}
+			case token.CONST:
+				place(node.Doc, placeConst)
+				for _, spec := range node.Specs {
+					place(spec.(*ast.ValueSpec).Doc, placeConst)
+					place(spec.(*ast.ValueSpec).Comment, placeConst)
+				}
+			}
+		case *ast.StructType:
+			// anonymous structs; declared ones are placed with their type
+			c.placeFields(node, false)
+		case *ast.FuncDecl:
+			placement := placeFunc
+			if node.Recv != nil {
+				placement = placeMethod
+			}
+			place(node.Doc, placement)
+			if node.Doc != nil {
+				for _, comment := range node.Doc.List {
+					c.funcs[comment] = node
+				}
+			}
+			fieldList(node.Recv)
+			fieldList(node.Type.Params)
+		case *ast.FuncLit:
+			fieldList(node.Type.Params)
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				c.defines[c.pass.Fset.Position(node.Pos()).Line] = true
+			}
+		}
+		return true
+	})
+}
+
+// placeFields records the doc and line comments of the fields of st
+func (c *annotationChecker) placeFields(st *ast.StructType, immutable bool) {
+	for _, field := range st.Fields.List {
+		for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+			if group == nil {
+				continue
+			}
+			for _, comment := range group.List {
+				if _, placed := c.fields[comment]; !placed {
+					c.placements[comment] = placeField
+					c.fields[comment] = immutable
+				}
+			}
+		}
+	}
+}
+
+// isImmutableTypeSpec reports whether spec declares a type the checker
+// treats as immutable
+func (c *annotationChecker) isImmutableTypeSpec(spec *ast.TypeSpec) bool {
+	obj, ok := c.pass.TypesInfo.Defs[spec.Name].(*types.TypeName)
+	if !ok {
+		return false
+	}
+	_, immutable := c.result.immutableTypes[obj]
+	return immutable
+}
+
+// placementOf returns where comment sits
+func (c *annotationChecker) placementOf(comment *ast.Comment) string {
+	for _, params := range c.params {
+		if comment.Pos() > params[0] && comment.End() <= params[1] {
+			return placeParam
+		}
+	}
+	if placement, ok := c.placements[comment]; ok {
+		return placement
+	}
+	if c.defines[c.pass.Fset.Position(comment.Pos()).Line] {
+		return placeDefine
+	}
+	return placeStatement
+}
+
+// checkDirective reports d, a directive of a known kind, when it takes no
+// effect where it is placed or adds nothing to what is already in effect
+func (c *annotationChecker) checkDirective(d directive, kind string, skipRedundant bool) {
+	keys := make([]string, 0, len(d.options))
+	for key := range d.options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !containsString(directiveOptions[kind], key) {
+			c.report(d, annotationUnknown, "unknown option %s of %s", key, d.name)
+		}
+	}
+
+	if kind == directiveAllowMutate {
+		if !skipRedundant && !c.result.suppressed[lineStart(c.pass.Fset, d.comment.Pos())] {
+			c.report(d, annotationRedundant, "%s suppresses nothing, there is no mutation on this line", d.name)
+		}
+		return
+	}
+
+	placement := c.placementOf(d.comment)
+	allowed := directivePlacements[kind]
+	if !containsString(allowed.places, placement) {
+		c.report(d, annotationMisplaced, "%s has no effect on %s, it applies to %s", d.name, placement, allowed.where)
+		return
+	}
+
+	switch kind {
+	case directiveImmutable:
+		if d.hasArg(argDeep) && placement != placeVar && placement != placeDefine {
+			c.report(d, annotationRedundant, "%s deep on %s, which is immutable all the way down already", d.name, placement)
+		}
+		if placement == placeField && c.fields[d.comment] {
+			c.report(d, annotationRedundant, "%s on a field of an @immutable struct, which is immutable already", d.name)
+		}
+	case directiveMutable:
+		if !c.fields[d.comment] {
+			c.report(d, annotationRedundant, "%s on a field of a struct that is not @immutable, which is mutable already", d.name)
+		}
+	case directiveReadonly:
+		if decl := c.funcs[d.comment]; decl != nil {
+			c.checkReadonlyDoc(d, decl)
+		}
+	}
+}
+
+// checkReadonlyDoc reports a read-only directive in the doc comment of decl
+// naming something that is not a parameter, naming nothing on a function,
+// or repeating what a pure keyword already implies
+func (c *annotationChecker) checkReadonlyDoc(d directive, decl *ast.FuncDecl) {
+	if hasDirective(decl.Doc, directivePure, c.result.settings.PureKeywords) {
+		c.report(d, annotationRedundant, "%s on a @pure function, whose parameters and receiver are read-only already", d.name)
+		return
+	}
+	params := make(map[string]bool)
+	for _, list := range []*ast.FieldList{decl.Recv, decl.Type.Params} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				params[name.Name] = true
+			}
+		}
+	}
+
+	names := readonlyArgNames(d)
+	for _, name := range names {
+		if !params[name] {
+			c.report(d, annotationMisplaced, "%s names %s, which is not a parameter of %s", d.name, name, decl.Name.Name)
+		}
+	}
+	if len(names) == 0 && decl.Recv == nil {
+		c.report(d, annotationMisplaced, "%s names no parameter of %s, only methods may be read-only as a whole", d.name, decl.Name.Name)
+	}
+}
+
+// reportUnknown reports a directive with an unknown name, suggesting the
+// closest known keyword when there is one
+func (c *annotationChecker) reportUnknown(d directive) {
+	if keyword := closestKeyword(d.name, c.known); keyword != "" {
+		c.report(d, annotationMisspelled, "unknown annotation %s, did you mean %s?", d.name, keyword)
+		return
+	}
+	c.report(d, annotationUnknown, "unknown annotation %s", d.name)
+}
+
+func (c *annotationChecker) report(d directive, category string, format string, args ...any) {
+	c.pass.Report(analysis.Diagnostic{
+		Pos:      d.comment.Pos(),
+		End:      d.comment.End(),
+		Category: category,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// closestKeyword returns the known keyword name most likely meant: one it
+// differs from in case only, one it extends (@immutables, @immutable-ish),
+// or one within a small edit distance. Ties go to the keyword sharing the
+// longest prefix with name.
+func closestKeyword(name string, known map[string]string) string {
+	lower := strings.ToLower(name)
+	best, bestDist, bestPrefix := "", 0, 0
+	for keyword := range known {
+		kw := strings.ToLower(keyword)
+		dist := editDistance(lower, kw)
+		if strings.HasPrefix(lower, kw) {
+			dist = 1
+		}
+		if dist > 2 || dist*3 > len(kw) {
+			continue
+		}
+		prefix := commonPrefixLen(lower, kw)
+		if best == "" || dist < bestDist || (dist == bestDist && (prefix > bestPrefix || (prefix == bestPrefix && keyword < best))) {
+			best, bestDist, bestPrefix = keyword, dist, prefix
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func commonPrefixLen(a string, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// checkBuiltinCall reports builtin calls that write into an immutable map or
+// slice without an assignment: delete, clear, copy, and append on a re-slice
+func checkBuiltinCall(ctx *analysisCtx, call *ast.CallExpr) {
+	ident, ok := stripParens(call.Fun).(*ast.Ident)
+	if !ok || len(call.Args) == 0 {
+		return
+	}
+	builtin, ok := ctx.pass.TypesInfo.Uses[ident].(*types.Builtin)
+	if !ok {
+		return
+	}
+
+	dst := call.Args[0]
+	var helpMsg string
+	switch builtin.Name() {
+	case "delete":
+		helpMsg = "deleting a key from an immutable map"
+	case "clear":
+		helpMsg = "clearing an immutable map or slice"
+	case "copy":
+		helpMsg = "copying into an immutable slice overwrites its elements"
+	case "append":
+		// append(im.Arr, x) only writes past the end of im.Arr, but appending
+		// to a shorter re-slice overwrites elements that im.Arr still sees,
+		// unless its capacity equals its length (im.Arr[:1:1]), which forces
+		// a fresh backing array. im.Arr[:1:2] still has room for one more.
+		// s := im.Arr[:1]; s = append(s, x) writes through the re-slice as well.
+		if !isReslice(ctx, dst) {
+			return
+		}
+		helpMsg = "appending to a re-slice of an immutable slice overwrites its shared backing array"
+	default:
+		return
+	}
+
+	if !isImmutableMutationWithAliases(ctx.pass, dst, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) || isUnderConstruction(ctx, dst) {
+		return
+	}
+
+	reportMutation(ctx, call.Pos(), getExpressionString(dst), dst, ruleBuiltin, helpMsg)
+}
+
+// isReslice reports whether expr is a re-slice that append can write through:
+// a slice expression with room past its length, or a variable aliasing one
+func isReslice(ctx *analysisCtx, expr ast.Expr) bool {
+	switch e := stripParens(expr).(type) {
+	case *ast.SliceExpr:
+		return hasSpareCapacity(ctx.pass, e)
+	case *ast.Ident:
+		return ctx.reslices[ctx.pass.TypesInfo.ObjectOf(e)]
+	}
+	return false
+}
+
+// hasSpareCapacity reports whether slice may have capacity past its length,
+// so that appending to it writes into the backing array of the sliced
+// operand. Only a full slice expression whose max provably equals its high
+// bound, im.Arr[:1:1] or im.Arr[i:n:n], has none.
+func hasSpareCapacity(pass *analysis.Pass, slice *ast.SliceExpr) bool {
+	if !slice.Slice3 {
+		return true
+	}
+	high, max := pass.TypesInfo.Types[slice.High].Value, pass.TypesInfo.Types[slice.Max].Value
+	if high != nil && max != nil {
+		return !constant.Compare(high, token.EQL, max)
+	}
+	return !isSameVariable(pass, slice.High, slice.Max)
+}
+
+// isSameVariable reports whether a and b read the same variable, n or c.n,
+// without calls in between that could change it
+func isSameVariable(pass *analysis.Pass, a ast.Expr, b ast.Expr) bool {
+	switch x := stripParens(a).(type) {
+	case *ast.Ident:
+		y, ok := stripParens(b).(*ast.Ident)
+		return ok && pass.TypesInfo.ObjectOf(x) != nil && pass.TypesInfo.ObjectOf(x) == pass.TypesInfo.ObjectOf(y)
+	case *ast.SelectorExpr:
+		y, ok := stripParens(b).(*ast.SelectorExpr)
+		return ok && pass.TypesInfo.ObjectOf(x.Sel) == pass.TypesInfo.ObjectOf(y.Sel) && isSameVariable(pass, x.X, y.X)
+	}
+	return false
+}
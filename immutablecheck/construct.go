@@ -0,0 +1,397 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/cfg"
+)
+
+// isConstructor reports whether fnNode is a function declaration annotated
+// with a constructor keyword, or named after Settings.ConstructorPattern.
+// Inside a constructor, immutable values it allocates itself may be
+// initialised field by field until they escape, see escapeUses.
+func (pc *passCollector) isConstructor(fnNode ast.Node) bool {
+	decl, ok := fnNode.(*ast.FuncDecl)
+	if !ok {
+		return false
+	}
+	if hasDirective(decl.Doc, directiveConstructor, pc.settings.ConstructorKeywords) {
+		return true
+	}
+	return pc.constructorPattern != nil && pc.constructorPattern.MatchString(decl.Name.Name)
+}
+
+// isConstructedVar reports whether expr is a variable of an immutable type,
+// or a pointer to one, which a constructor may initialise
+func (pc *passCollector) isConstructedVar(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && isImmutableVariable(pc.pass, ident, pc.immutableTypes, pc.varToTypeAlias)
+}
+
+// isFreshAllocation reports whether expr allocates a new value nobody else
+// refers to yet: T{...}, &T{...}, new(T) or make(...)
+func isFreshAllocation(pass *analysis.Pass, expr ast.Expr) bool {
+	switch e := stripParens(expr).(type) {
+	case *ast.CompositeLit:
+		return true
+	case *ast.UnaryExpr:
+		_, ok := stripParens(e.X).(*ast.CompositeLit)
+		return e.Op == token.AND && ok
+	case *ast.CallExpr:
+		ident, ok := stripParens(e.Fun).(*ast.Ident)
+		if !ok {
+			return false
+		}
+		builtin, ok := pass.TypesInfo.Uses[ident].(*types.Builtin)
+		return ok && (builtin.Name() == "new" || builtin.Name() == "make")
+	}
+	return false
+}
+
+// isUnderConstruction reports whether expr writes to, or passes along, a
+// value its constructor allocated and has not let escape yet: c.Port,
+// c.Tags["k"], *c or &c.Inner where c is fresh at this program point
+func isUnderConstruction(ctx *analysisCtx, expr ast.Expr) bool {
+	for {
+		switch e := stripParens(expr).(type) {
+		case *ast.Ident:
+			obj := ctx.pass.TypesInfo.ObjectOf(e)
+			return obj != nil && ctx.constructing[obj]
+		case *ast.SelectorExpr:
+			if _, ok := ctx.pass.TypesInfo.Selections[e]; !ok {
+				return false // qualified identifier
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.UnaryExpr:
+			if e.Op != token.AND {
+				return false
+			}
+			expr = e.X
+		default:
+			return false
+		}
+	}
+}
+
+// computeLocalReceivers finds the methods with a pointer receiver that do not
+// let it escape: their bodies only read and write through the receiver and
+// call methods of the same kind on it. Every method starts out local and
+// loses the property until nothing changes, so recursive methods stay local.
+// The result is exported as a localReceiverFact for importers.
+func (pc *passCollector) computeLocalReceivers() {
+	putLog(info, "started computing local receivers")
+
+	methods := make(map[*types.Func]*ast.FuncDecl)
+	for _, file := range pc.pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || fn.Recv == nil {
+				continue
+			}
+			if obj, ok := pc.pass.TypesInfo.Defs[fn.Name].(*types.Func); ok && isPointer(obj.Type().(*types.Signature).Recv().Type()) {
+				methods[obj] = fn
+				pc.localReceivers[obj] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for fn, decl := range methods {
+			if pc.localReceivers[fn] && pc.receiverEscapes(decl) {
+				pc.localReceivers[fn] = false
+				changed = true
+			}
+		}
+	}
+
+	for fn, local := range pc.localReceivers {
+		if local {
+			pc.pass.ExportObjectFact(fn, new(localReceiverFact))
+		}
+	}
+
+	putLog(info, "finished computing local receivers")
+}
+
+// receiverEscapes reports whether the body of decl lets its receiver escape,
+// judged by escapeUses as if the receiver were a value under construction.
+// A receiver captured by a function literal escapes.
+func (pc *passCollector) receiverEscapes(decl *ast.FuncDecl) bool {
+	names := decl.Recv.List[0].Names
+	if len(names) == 0 {
+		return false
+	}
+	recv := pc.pass.TypesInfo.Defs[names[0]]
+	if recv == nil {
+		return false
+	}
+
+	captured := false
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			ast.Inspect(lit.Body, func(m ast.Node) bool {
+				if ident, ok := m.(*ast.Ident); ok && pc.pass.TypesInfo.Uses[ident] == recv {
+					captured = true
+				}
+				return !captured
+			})
+			return false
+		}
+		return !captured
+	})
+	if captured {
+		return true
+	}
+
+	state := flowState{recv: varState{fresh: true}}
+	for _, b := range cfg.New(decl.Body, mayReturn).Blocks {
+		for _, node := range b.Nodes {
+			pc.escapeUses(node, state)
+		}
+	}
+	return !state[recv].fresh
+}
+
+// isLocalReceiver reports whether fn is a method with a pointer receiver that
+// does not let it escape, in this package or in a dependency
+func (pc *passCollector) isLocalReceiver(fn *types.Func) bool {
+	fn = fn.Origin()
+	if fn.Pkg() == pc.pass.Pkg {
+		return pc.localReceivers[fn]
+	}
+	return pc.pass.ImportObjectFact(fn, new(localReceiverFact))
+}
+
+// escapeUses clears the fresh state of every variable that node lets
+// escape. Writing through a fresh value, calling its value methods and its
+// methods known not to let the receiver escape, and reading plain values out
+// of it keep it under construction; any other use (returning it, storing or
+// sending it, passing it or a reference into it to a function or a pointer
+// receiver, taking its address) publishes it, after which it is immutable
+// again.
+func (pc *passCollector) escapeUses(node ast.Node, state flowState) {
+	fresh := false
+	for _, st := range state {
+		fresh = fresh || st.fresh
+	}
+	if !fresh {
+		return
+	}
+
+	v := &escapeVisitor{pc: pc, pass: pc.pass, state: state}
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range n.Lhs {
+			v.target(lhs)
+		}
+		for _, rhs := range n.Rhs {
+			v.value(rhs)
+		}
+	case *ast.IncDecStmt:
+		v.target(n.X)
+	case *ast.ValueSpec:
+		for _, value := range n.Values {
+			v.value(value)
+		}
+	case *ast.ExprStmt:
+		v.value(n.X)
+	case *ast.ReturnStmt:
+		for _, result := range n.Results {
+			v.value(result)
+		}
+	case *ast.SendStmt:
+		v.value(n.Chan)
+		v.value(n.Value)
+	case *ast.GoStmt:
+		v.value(n.Call)
+	case *ast.DeferStmt:
+		v.value(n.Call)
+	case ast.Expr:
+		v.value(n)
+	}
+}
+
+// escapeVisitor walks the expressions of one CFG node for escapeUses
+type escapeVisitor struct {
+	pc    *passCollector
+	pass  *analysis.Pass
+	state flowState
+}
+
+// escape marks the variable ident refers to as published
+func (v *escapeVisitor) escape(ident *ast.Ident) {
+	obj := v.pass.TypesInfo.ObjectOf(ident)
+	if st, ok := v.state[obj]; ok && st.fresh {
+		st.fresh = false
+		setVarState(v.state, obj, st)
+	}
+}
+
+// target visits the left-hand side of an assignment
+func (v *escapeVisitor) target(expr ast.Expr) {
+	if _, ok := stripParens(expr).(*ast.Ident); ok {
+		return
+	}
+	v.access(expr)
+}
+
+// access visits a selector, index or dereference chain that reads or writes
+// storage inside its root variable without letting the root escape
+func (v *escapeVisitor) access(expr ast.Expr) {
+	switch e := stripParens(expr).(type) {
+	case *ast.Ident:
+	case *ast.SelectorExpr:
+		if _, ok := v.pass.TypesInfo.Selections[e]; ok {
+			v.access(e.X)
+		}
+	case *ast.IndexExpr:
+		v.access(e.X)
+		v.value(e.Index)
+	case *ast.StarExpr:
+		v.access(e.X)
+	default:
+		v.value(expr)
+	}
+}
+
+// published visits a chain whose value refers to storage of its root
+// variable, which therefore escapes
+func (v *escapeVisitor) published(expr ast.Expr) {
+	switch e := stripParens(expr).(type) {
+	case *ast.Ident:
+		v.escape(e)
+	case *ast.SelectorExpr:
+		if _, ok := v.pass.TypesInfo.Selections[e]; ok {
+			v.published(e.X)
+		}
+	case *ast.IndexExpr:
+		v.published(e.X)
+		v.value(e.Index)
+	case *ast.StarExpr:
+		v.published(e.X)
+	default:
+		v.value(expr)
+	}
+}
+
+// value visits an expression whose result is used as a value
+func (v *escapeVisitor) value(expr ast.Expr) {
+	if expr == nil {
+		return
+	}
+	switch e := stripParens(expr).(type) {
+	case *ast.Ident:
+		v.escape(e)
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+		// plain values read out of a fresh value are copies
+		if typ := v.pass.TypesInfo.TypeOf(e); typ != nil && isBasic(typ) {
+			v.access(e)
+		} else {
+			v.published(e)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			v.published(e.X)
+		} else {
+			v.value(e.X)
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.EQL || e.Op == token.NEQ {
+			// comparing c != nil does not publish c
+			v.access(e.X)
+			v.access(e.Y)
+		} else {
+			v.value(e.X)
+			v.value(e.Y)
+		}
+	case *ast.CallExpr:
+		v.call(e)
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			v.value(elt)
+		}
+	case *ast.KeyValueExpr:
+		v.value(e.Value)
+	case *ast.SliceExpr:
+		v.published(e.X)
+		v.value(e.Low)
+		v.value(e.High)
+		v.value(e.Max)
+	case *ast.TypeAssertExpr:
+		v.value(e.X)
+	case *ast.FuncLit:
+		// variables captured by closures are never tracked
+	}
+}
+
+// throughMutableField reports whether the method selected by fun is called
+// on a mutable field, directly (c.mu.Lock()) or promoted (c.Lock())
+func (v *escapeVisitor) throughMutableField(fun *ast.SelectorExpr) bool {
+	if selectsMutableField(v.pass, fun) {
+		return true
+	}
+	x, ok := stripParens(fun.X).(*ast.SelectorExpr)
+	return ok && selectsMutableField(v.pass, x)
+}
+
+func isBasic(typ types.Type) bool {
+	_, ok := typ.Underlying().(*types.Basic)
+	return ok
+}
+
+// call visits a call: the receiver of a method call that does not let it
+// escape, the operands of len, cap, delete, clear and copy and the slice
+// appended to are accessed, every other argument is used as a value
+func (v *escapeVisitor) call(call *ast.CallExpr) {
+	switch fun := stripParens(call.Fun).(type) {
+	case *ast.Ident:
+		if builtin, ok := v.pass.TypesInfo.Uses[fun].(*types.Builtin); ok {
+			switch builtin.Name() {
+			case "len", "cap", "delete", "clear", "copy":
+				for _, arg := range call.Args {
+					v.access(arg)
+				}
+				return
+			case "append":
+				// c.Hosts = append(c.Hosts, h) grows c's own slice
+				if len(call.Args) > 0 {
+					v.access(call.Args[0])
+					for _, arg := range call.Args[1:] {
+						v.value(arg)
+					}
+				}
+				return
+			}
+		}
+	case *ast.SelectorExpr:
+		if selection, ok := v.pass.TypesInfo.Selections[fun]; ok && selection.Kind() == types.MethodVal {
+			// a pointer receiver may be stored away (registry = append(registry, c)),
+			// but storing a mutable field (c.mu.Lock()) exposes nothing immutable
+			fn, ok := selection.Obj().(*types.Func)
+			if ok && isPointer(fn.Type().(*types.Signature).Recv().Type()) && !v.pc.isLocalReceiver(fn) && !v.throughMutableField(fun) {
+				v.published(fun.X)
+			} else {
+				v.access(fun.X)
+			}
+			for _, arg := range call.Args {
+				v.value(arg)
+			}
+			return
+		}
+	}
+
+	if tv, ok := v.pass.TypesInfo.Types[call.Fun]; !ok || !tv.IsType() {
+		v.value(call.Fun)
+	}
+	for _, arg := range call.Args {
+		v.value(arg)
+	}
+}
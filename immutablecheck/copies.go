@@ -0,0 +1,137 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// isCopyAssignment reports whether `lhs = rhs` leaves lhs holding a private
+// copy of an immutable value: always for copies out of a map or slice, and
+// with Settings.CopySemantics also for copies of other variables, fields and
+// dereferenced pointers (c := im, c := outer.Inner, c := *imPtr)
+func (pc *passCollector) isCopyAssignment(lhs ast.Expr, rhs ast.Expr) bool {
+	if pc.isIndexCopy(lhs, rhs) {
+		return true
+	}
+	if !pc.settings.CopySemantics {
+		return false
+	}
+	switch stripParens(rhs).(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.StarExpr:
+	default:
+		return false
+	}
+	ident, ok := lhs.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	return pc.isValueCopyVar(pc.pass.TypesInfo.ObjectOf(ident))
+}
+
+// isValueCopyVar reports whether obj is a variable of an immutable type that
+// holds its value inline (a struct, array or basic type), so that assigning
+// it copies rather than shares the value
+func (pc *passCollector) isValueCopyVar(obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	if !ok || v.IsField() || !isImmutableType(v.Type(), pc.immutableTypes) {
+		return false
+	}
+	switch v.Type().Underlying().(type) {
+	case *types.Struct, *types.Array, *types.Basic:
+		return true
+	}
+	return false
+}
+
+// valueCopyParams returns the receiver and parameters of a function that
+// receive a copy of an immutable value under Settings.CopySemantics. Read-only
+// parameters are never private copies.
+func (pc *passCollector) valueCopyParams(fnNode ast.Node) []types.Object {
+	if !pc.settings.CopySemantics {
+		return nil
+	}
+
+	var lists []*ast.FieldList
+	switch fn := fnNode.(type) {
+	case *ast.FuncDecl:
+		lists = append(lists, fn.Recv, fn.Type.Params)
+	case *ast.FuncLit:
+		lists = append(lists, fn.Type.Params)
+	}
+
+	var params []types.Object
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				if obj := pc.pass.TypesInfo.Defs[name]; pc.isValueCopyVar(obj) && !pc.readonly[obj] {
+					params = append(params, obj)
+				}
+			}
+		}
+	}
+	return params
+}
+
+// isRangeValueCopy reports whether the value variable of rng receives a copy
+// of each immutable element under Settings.CopySemantics
+func (pc *passCollector) isRangeValueCopy(rng *ast.RangeStmt) bool {
+	if !pc.settings.CopySemantics || rng.Value == nil {
+		return false
+	}
+	ident, ok := rng.Value.(*ast.Ident)
+	return ok && pc.isValueCopyVar(pc.pass.TypesInfo.ObjectOf(ident))
+}
+
+// isCopyWrite reports whether writing expr only changes a private copy of an
+// immutable value. By default that is a direct field of a copy (v.Num). With
+// Settings.CopySemantics the write may also reach into structs and arrays the
+// copy holds inline (c.Cll.Value, c.Grid[0]), but not through the maps,
+// slices and pointers it still shares with the original (c.Arr[0], *c.Ptr).
+func isCopyWrite(ctx *analysisCtx, expr ast.Expr) bool {
+	if !ctx.settings.CopySemantics {
+		sel, ok := stripParens(expr).(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		base, ok := stripParens(sel.X).(*ast.Ident)
+		if !ok {
+			return false
+		}
+		obj := ctx.pass.TypesInfo.ObjectOf(base)
+		return obj != nil && ctx.copiedVariables[obj]
+	}
+
+	for {
+		switch e := stripParens(expr).(type) {
+		case *ast.Ident:
+			obj := ctx.pass.TypesInfo.ObjectOf(e)
+			return obj != nil && ctx.copiedVariables[obj]
+		case *ast.SelectorExpr:
+			selection, ok := ctx.pass.TypesInfo.Selections[e]
+			if !ok || selection.Kind() != types.FieldVal || selection.Indirect() {
+				return false
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			if _, ok := ctx.pass.TypesInfo.TypeOf(e.X).Underlying().(*types.Array); !ok {
+				return false
+			}
+			expr = e.X
+		default:
+			return false
+		}
+	}
+}
+
+// isCopyReassignment reports whether `ident = ...` replaces a private copy
+// under Settings.CopySemantics, which cannot affect the original value
+func isCopyReassignment(ctx *analysisCtx, ident *ast.Ident) bool {
+	if !ctx.settings.CopySemantics {
+		return false
+	}
+	obj := ctx.pass.TypesInfo.ObjectOf(ident)
+	return obj != nil && ctx.copiedVariables[obj]
+}
@@ -0,0 +1,199 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// directivePrefix namespaces the Go-style spelling of every directive,
+// //pls:immutable, which gofmt leaves alone and go/ast keeps out of doc text
+const directivePrefix = "pls:"
+
+// directive kinds, one per keyword setting. The namespaced spelling of a
+// directive is directivePrefix followed by its kind.
+const (
+	directiveImmutable   = "immutable"
+	directiveMutable     = "mutable"
+	directiveConstructor = "constructor"
+	directiveReadonly    = "readonly"
+	directivePure        = "pure"
+	directiveFrozenAfter = "frozen-after"
+	directiveAllowMutate = "allow-mutate"
+)
+
+// directive options and arguments
+const (
+	optionReason = "reason" // //pls:allow-mutate reason="..."
+	argDeep      = "deep"   // //pls:immutable deep
+)
+
+// directiveOptions lists the key="value" options each kind of directive takes
+var directiveOptions = map[string][]string{
+	directiveAllowMutate: {optionReason},
+}
+
+// directive is a comment parsed with the directive grammar: a name at the
+// very start of the comment, // @immutable, or a namespaced name right after
+// the comment marker, //pls:immutable, followed by arguments separated by
+// white space. Arguments are plain words (//pls:immutable deep, // @readonly
+// cfg, opts) or key="value" options (//pls:allow-mutate reason="warm-up").
+// @immutable inside prose is not a directive.
+type directive struct {
+	comment    *ast.Comment
+	name       string
+	namespaced bool
+	args       []string
+	options    map[string]string
+}
+
+// parseDirective parses comment with the directive grammar. Any comment
+// holding a word parses; is tells whether it names a directive.
+func parseDirective(comment *ast.Comment) (directive, bool) {
+	text := comment.Text
+	if strings.HasPrefix(text, "/*") {
+		text = strings.TrimSuffix(text[2:], "*/")
+	} else {
+		text = strings.TrimPrefix(text, "//")
+	}
+	namespaced := strings.HasPrefix(text, directivePrefix)
+
+	words := splitDirective(text)
+	if len(words) == 0 {
+		return directive{}, false
+	}
+	d := directive{comment: comment, name: words[0], namespaced: namespaced}
+	for _, word := range words[1:] {
+		key, value, ok := strings.Cut(word, "=")
+		if !ok || !token.IsIdentifier(key) {
+			d.args = append(d.args, word)
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if d.options == nil {
+			d.options = make(map[string]string)
+		}
+		d.options[key] = value
+	}
+	return d, true
+}
+
+// splitDirective splits text at white space, keeping double-quoted strings,
+// reason="two words", in one piece
+func splitDirective(text string) []string {
+	var words []string
+	var word strings.Builder
+	quoted, escaped := false, false
+	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// is reports whether d is a directive of kind: one of keywords, or the
+// namespaced name of kind
+func (d directive) is(kind string, keywords []string) bool {
+	if d.namespaced {
+		return d.name == directivePrefix+kind
+	}
+	return containsString(keywords, d.name)
+}
+
+// hasArg reports whether d holds the plain argument arg
+func (d directive) hasArg(arg string) bool {
+	return containsString(d.args, arg)
+}
+
+// commentDirective returns comment parsed as a directive of kind
+func commentDirective(comment *ast.Comment, kind string, keywords []string) (directive, bool) {
+	d, ok := parseDirective(comment)
+	if !ok || !d.is(kind, keywords) {
+		return directive{}, false
+	}
+	return d, true
+}
+
+// findDirective returns the first directive of kind in group
+func findDirective(group *ast.CommentGroup, kind string, keywords []string) (directive, bool) {
+	if group == nil {
+		return directive{}, false
+	}
+	for _, comment := range group.List {
+		if d, ok := commentDirective(comment, kind, keywords); ok {
+			return d, true
+		}
+	}
+	return directive{}, false
+}
+
+// hasDirective reports whether group holds a directive of kind
+func hasDirective(group *ast.CommentGroup, kind string, keywords []string) bool {
+	_, ok := findDirective(group, kind, keywords)
+	return ok
+}
+
+// lineDirective returns the directive of kind in a comment on the line of
+// pos, as in x := compute() // @immutable
+func lineDirective(pass *analysis.Pass, pos token.Pos, commentGroups []*ast.CommentGroup, kind string, keywords []string) (directive, bool) {
+	line := pass.Fset.Position(pos).Line
+	for _, cg := range commentGroups {
+		for _, comment := range cg.List {
+			// ONLY allow inline comments on the exact same line as the statement
+			if pass.Fset.Position(comment.Pos()).Line != line {
+				continue
+			}
+			if d, ok := commentDirective(comment, kind, keywords); ok {
+				return d, true
+			}
+		}
+	}
+	return directive{}, false
+}
+
+// hasImmutableComment reports whether the doc comment of genDecl holds an
+// immutable directive
+func hasImmutableComment(genDecl *ast.GenDecl, keywords []string) bool {
+	return hasDirective(genDecl.Doc, directiveImmutable, keywords)
+}
+
+// hasAllowMutateComment checks if a statement has an @allow-mutate directive
+// The directive MUST be an inline comment directly after the statement on the same line.
+// Format: x = "value" //@allow-mutate  OR  x = "value" // @allow-mutate  OR
+// x = "value" //pls:allow-mutate reason="..."
+// Comments on lines above or below the statement are NOT supported.
+// ^^^ this just causes a lot of problems with how go AST groups together comments in a CommentGroup
+func hasAllowMutateComment(pass *analysis.Pass, pos token.Pos, commentGroups []*ast.CommentGroup, keywords []string) bool {
+	_, ok := lineDirective(pass, pos, commentGroups, directiveAllowMutate, keywords)
+	return ok
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
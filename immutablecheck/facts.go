@@ -0,0 +1,102 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// immutableFact is attached to every package-level type name marked @immutable
+// so that packages importing it enforce the same immutability guarantees
+type immutableFact struct{}
+
+func (*immutableFact) AFact() {}
+
+func (*immutableFact) String() string { return "immutable" }
+
+// exportImmutableFact records obj as immutable for downstream packages.
+// Function-local types cannot be referenced from other packages, so only
+// package-level type names are exported.
+func exportImmutableFact(pass *analysis.Pass, obj *types.TypeName) {
+	if obj.Pkg() != pass.Pkg || obj.Parent() != pass.Pkg.Scope() {
+		return
+	}
+	pass.ExportObjectFact(obj, new(immutableFact))
+}
+
+// importedImmutableTypes returns every type name from a dependency that was
+// marked @immutable in its own package
+func importedImmutableTypes(pass *analysis.Pass) []*types.TypeName {
+	var imported []*types.TypeName
+	for _, fact := range pass.AllObjectFacts() {
+		if _, ok := fact.Fact.(*immutableFact); !ok {
+			continue
+		}
+		typeName, ok := fact.Object.(*types.TypeName)
+		if !ok || typeName.Pkg() == pass.Pkg {
+			continue
+		}
+		imported = append(imported, typeName)
+	}
+	return imported
+}
+
+// immutableFieldFact is attached to every struct field annotated @immutable,
+// or tagged immutable:"true", inside an otherwise mutable struct
+type immutableFieldFact struct{}
+
+func (*immutableFieldFact) AFact() {}
+
+func (*immutableFieldFact) String() string { return "immutable field" }
+
+// mutableFieldFact is attached to every struct field annotated @mutable, or
+// tagged immutable:"false", which stays mutable inside an immutable struct
+type mutableFieldFact struct{}
+
+func (*mutableFieldFact) AFact() {}
+
+func (*mutableFieldFact) String() string { return "mutable field" }
+
+// readonlyMethodFact is attached to every method annotated @readonly, which
+// does not mutate its receiver
+type readonlyMethodFact struct{}
+
+func (*readonlyMethodFact) AFact() {}
+
+func (*readonlyMethodFact) String() string { return "readonly method" }
+
+// localReceiverFact is attached to every method with a pointer receiver that
+// does not let the receiver escape, so that constructors, here and in
+// importers, may call it on a value under construction
+type localReceiverFact struct{}
+
+func (*localReceiverFact) AFact() {}
+
+func (*localReceiverFact) String() string { return "local receiver" }
+
+// immutableVarFact is attached to every package-level variable annotated
+// @immutable, which may not be assigned after its declaration. Deep is set
+// when the storage it refers to is frozen too.
+type immutableVarFact struct {
+	Deep bool
+}
+
+func (*immutableVarFact) AFact() {}
+
+func (f *immutableVarFact) String() string {
+	if f.Deep {
+		return "deep immutable variable"
+	}
+	return "immutable variable"
+}
+
+// readonlyParamsFact lists the read-only parameters of a function or method,
+// so that strict mode may pass read-only references to it across packages
+type readonlyParamsFact struct {
+	Params []int // indices of read-only parameters, sorted
+}
+
+func (*readonlyParamsFact) AFact() {}
+
+func (f *readonlyParamsFact) String() string { return fmt.Sprintf("readonly params %v", f.Params) }
@@ -0,0 +1,219 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// immutableTagKey is the struct tag key marking a single field as immutable,
+// ID string `immutable:"true"`, or as mutable inside an immutable struct,
+// hits int `immutable:"false"`
+const immutableTagKey = "immutable"
+
+// collectFieldAnnotations records the fields of st annotated individually:
+// immutable ones, marked with an immutable keyword in their doc or line
+// comment or with immutable:"true", and mutable ones exempt from the
+// immutability of their struct, marked with a mutable keyword or with
+// immutable:"false". The facts are exported for every such field; fields of
+// types that are not reachable from other packages stay local to this one.
+func (pc *passCollector) collectFieldAnnotations(st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		var fact analysis.Fact
+		switch fieldAnnotation(field, pc.settings) {
+		case annotatedImmutable:
+			fact = new(immutableFieldFact)
+		case annotatedMutable:
+			fact = new(mutableFieldFact)
+		default:
+			continue
+		}
+		names := field.Names
+		if len(names) == 0 {
+			// an embedded field is named after its type
+			if ident := embeddedFieldIdent(field.Type); ident != nil {
+				names = []*ast.Ident{ident}
+			}
+		}
+		for _, name := range names {
+			if v, ok := pc.pass.TypesInfo.ObjectOf(name).(*types.Var); ok && v.IsField() {
+				pc.pass.ExportObjectFact(v, fact)
+			}
+		}
+	}
+}
+
+type fieldAnnotationKind int
+
+const (
+	notAnnotated fieldAnnotationKind = iota
+	annotatedImmutable
+	annotatedMutable
+)
+
+// fieldAnnotation returns how field is annotated by its doc or line comment,
+// or by its immutable struct tag
+func fieldAnnotation(field *ast.Field, settings *Settings) fieldAnnotationKind {
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			d, ok := parseDirective(comment)
+			switch {
+			case !ok:
+			case d.is(directiveImmutable, settings.ImmutableKeywords):
+				return annotatedImmutable
+			case d.is(directiveMutable, settings.MutableKeywords):
+				return annotatedMutable
+			}
+		}
+	}
+	if field.Tag == nil {
+		return notAnnotated
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return notAnnotated
+	}
+	value, ok := reflect.StructTag(tag).Lookup(immutableTagKey)
+	if !ok {
+		return notAnnotated
+	}
+	immutable, err := strconv.ParseBool(value)
+	switch {
+	case err != nil:
+		return notAnnotated
+	case immutable:
+		return annotatedImmutable
+	}
+	return annotatedMutable
+}
+
+// embeddedFieldIdent returns the identifier naming an embedded field: T,
+// *T, pkg.T or T[P]
+func embeddedFieldIdent(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedFieldIdent(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedFieldIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldIdent(e.X)
+	}
+	return nil
+}
+
+// isImmutableField reports whether v is a struct field annotated as
+// immutable, in this package or in a dependency
+func isImmutableField(pass *analysis.Pass, v *types.Var) bool {
+	if v == nil || !v.IsField() {
+		return false
+	}
+	return pass.ImportObjectFact(v.Origin(), new(immutableFieldFact))
+}
+
+// isMutableField reports whether v is a struct field exempt from the
+// immutability of its struct: annotated as mutable, in this package or in a
+// dependency, or holding a sync or sync/atomic type, whose whole purpose is
+// to be changed through a shared value
+func isMutableField(pass *analysis.Pass, v *types.Var) bool {
+	if v == nil || !v.IsField() {
+		return false
+	}
+	return isInteriorMutableType(v.Type()) || pass.ImportObjectFact(v.Origin(), new(mutableFieldFact))
+}
+
+// interiorMutablePackages declare types meant to be mutated in place even
+// inside otherwise immutable values: mutexes, once, wait groups, atomics
+var interiorMutablePackages = map[string]bool{
+	"sync":        true,
+	"sync/atomic": true,
+}
+
+// isInteriorMutableType reports whether typ, or the type typ points to, is
+// declared in one of interiorMutablePackages
+func isInteriorMutableType(typ types.Type) bool {
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return interiorMutablePackages[named.Obj().Pkg().Path()]
+}
+
+// selectsImmutableField reports whether sel selects an immutable field,
+// either directly (u.ID) or promoted through an embedded field that is
+// itself annotated (u.Key, where the embedded Identity is @immutable)
+func selectsImmutableField(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	selection, ok := pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.FieldVal {
+		return false
+	}
+	for _, field := range selectedFields(selection) {
+		if isImmutableField(pass, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsMutableField reports whether sel selects a mutable field, or a
+// field or method promoted through one (im.mu, im.Lock() with an embedded
+// sync.Mutex)
+func selectsMutableField(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	selection, ok := pass.TypesInfo.Selections[sel]
+	return ok && throughMutableField(pass, selection)
+}
+
+// throughMutableField reports whether selection reaches its field or method
+// through a mutable field
+func throughMutableField(pass *analysis.Pass, selection *types.Selection) bool {
+	for _, field := range selectedFields(selection) {
+		if isMutableField(pass, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedFields returns the fields selection traverses: the embedded fields
+// a field or method is promoted through, followed by the field itself
+func selectedFields(selection *types.Selection) []*types.Var {
+	path := selection.Index()
+	if selection.Kind() != types.FieldVal {
+		path = path[:len(path)-1]
+	}
+	var fields []*types.Var
+	typ := selection.Recv()
+	for _, index := range path {
+		field := structField(typ, index)
+		if field == nil {
+			break
+		}
+		fields = append(fields, field)
+		typ = field.Type()
+	}
+	return fields
+}
+
+// structField returns field index of the struct typ, or *typ, refers to
+func structField(typ types.Type, index int) *types.Var {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok || index >= st.NumFields() {
+		return nil
+	}
+	return st.Field(index)
+}
@@ -0,0 +1,185 @@
+package immutablecheck
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// registerFlags exposes every option of settings as an analyzer flag, so the
+// same options work under singlechecker (-log), multichecker and go vet
+// -vettool (-immutablecheck.log)
+func registerFlags(fs *flag.FlagSet, settings *Settings) {
+	fs.Func("log", "log destination: stderr or a file path (default: no logging)", func(dest string) error {
+		SetLogDestination(dest)
+		return nil
+	})
+	fs.Func("loglevel", "minimum log level: error, warn, info or debug (default: debug)", SetLogLevel)
+	fs.Var((*stringList)(&settings.ImmutableKeywords), "immutable-keywords", "comma-separated comments that mark a type as immutable")
+	fs.Var((*stringList)(&settings.MutableKeywords), "mutable-keywords", "comma-separated comments that exempt a field of an immutable type")
+	fs.Var((*stringList)(&settings.ConstructorKeywords), "constructor-keywords", "comma-separated comments that mark a function as a constructor")
+	fs.Var((*patternFlag)(&settings.ConstructorPattern), "constructor-pattern", "regular expression matching the names of constructor functions, e.g. ^New")
+	fs.Var((*stringList)(&settings.ReadonlyKeywords), "readonly-keywords", "comma-separated comments that mark a parameter as read-only")
+	fs.Var((*stringList)(&settings.PureKeywords), "pure-keywords", "comma-separated comments that mark a function as free of side effects")
+	fs.Var((*stringList)(&settings.FrozenAfterKeywords), "frozen-after-keywords", "comma-separated comments that mark a type as immutable once its values are published")
+	fs.Var((*stringList)(&settings.AllowMutateKeywords), "allow-mutate-keywords", "comma-separated inline comments that suppress a report")
+	fs.BoolVar(&settings.ExemptTests, "exempt-tests", settings.ExemptTests, "do not report mutations in _test.go files")
+	fs.Var((*stringList)(&settings.ImmutableTypes), "immutable-types", "comma-separated fully-qualified names of additional immutable types")
+	fs.Var((*mutatorMap)(&settings.Mutators), "mutators", "comma-separated name=indices pairs of additional mutating functions, indices separated by ':' (-1 for the receiver)")
+	fs.BoolVar(&settings.CopySemantics, "copy-semantics", settings.CopySemantics, "allow mutating private value copies of immutable values outside their shared maps, slices and pointers")
+	fs.BoolVar(&settings.StrictReadonly, "strict-readonly", settings.StrictReadonly, "report read-only references flowing into parameters, fields and package-level variables that are not read-only")
+	fs.Var((*severityMap)(&settings.Severities), "severity", "comma-separated rule=severity pairs, severity is error, warning or off")
+	fs.Var((*formatFlag)(&settings.Format), "format", "diagnostic format: pretty or compact")
+	fs.Var((*engineFlag)(&settings.Engine), "engine", "mutation checker: ast or ssa")
+}
+
+// stringList is a flag.Value holding a comma-separated list. Setting it
+// replaces the defaults rather than appending to them.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// severityMap is a flag.Value holding rule=severity pairs
+type severityMap map[string]string
+
+func (m *severityMap) String() string {
+	if m == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*m))
+	for rule, sev := range *m {
+		pairs = append(pairs, rule+"="+sev)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *severityMap) Set(s string) error {
+	severities := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		rule, sev, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid severity %q (want rule=severity)", pair)
+		}
+		severities[strings.TrimSpace(rule)] = strings.TrimSpace(sev)
+	}
+	if err := (Settings{Severities: severities}).validate(); err != nil {
+		return err
+	}
+	*m = severities
+	return nil
+}
+
+// mutatorMap is a flag.Value holding name=indices pairs, e.g.
+// example.com/util.Fill=0,example.com/util.Swap=0:1
+type mutatorMap map[string][]int
+
+func (m *mutatorMap) String() string {
+	if m == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*m))
+	for name, indices := range *m {
+		fields := make([]string, len(indices))
+		for i, index := range indices {
+			fields[i] = strconv.Itoa(index)
+		}
+		pairs = append(pairs, name+"="+strings.Join(fields, ":"))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *mutatorMap) Set(s string) error {
+	mutators := make(map[string][]int)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid mutator %q (want name=indices)", pair)
+		}
+		indices, err := parseMutatorIndices(list)
+		if err != nil {
+			return fmt.Errorf("invalid mutator %q: %v", pair, err)
+		}
+		mutators[strings.TrimSpace(name)] = indices
+	}
+	if err := (Settings{Mutators: mutators}).validate(); err != nil {
+		return err
+	}
+	*m = mutators
+	return nil
+}
+
+// formatFlag is a flag.Value restricted to the supported diagnostic formats
+type formatFlag string
+
+func (f *formatFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *formatFlag) Set(s string) error {
+	if err := (Settings{Format: s}).validate(); err != nil {
+		return err
+	}
+	*f = formatFlag(s)
+	return nil
+}
+
+// engineFlag is a flag.Value restricted to the supported mutation engines
+type engineFlag string
+
+func (f *engineFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *engineFlag) Set(s string) error {
+	if err := (Settings{Engine: s}).validate(); err != nil {
+		return err
+	}
+	*f = engineFlag(s)
+	return nil
+}
+
+// patternFlag is a flag.Value holding a regular expression
+type patternFlag string
+
+func (f *patternFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *patternFlag) Set(s string) error {
+	if err := (Settings{ConstructorPattern: s}).validate(); err != nil {
+		return err
+	}
+	*f = patternFlag(s)
+	return nil
+}
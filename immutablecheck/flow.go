@@ -0,0 +1,385 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/cfg"
+)
+
+// maxFlowIterations bounds the fixed-point iteration over a function's CFG
+const maxFlowIterations = 64
+
+// varState is the alias/copy state of a local variable at a program point
+type varState struct {
+	alias    bool // shares storage with an immutable value
+	resliced bool // the shared storage is a re-slice, see isResliceSource
+	copied   bool // holds a private copy of an immutable value, see isCopyAssignment
+	fresh    bool // holds a value allocated by this constructor that has not escaped
+	frozen   bool // holds a @frozen-after value that has been published
+}
+
+// flowState maps tracked variables to their state; absent variables are neither
+type flowState map[types.Object]varState
+
+func (s flowState) clone() flowState {
+	c := make(flowState, len(s))
+	for obj, st := range s {
+		c[obj] = st
+	}
+	return c
+}
+
+func (s flowState) equal(other flowState) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for obj, st := range s {
+		if other[obj] != st {
+			return false
+		}
+	}
+	return true
+}
+
+// joinFlowStates merges the states flowing into a block: a variable may
+// alias immutable storage, or hold a published value, if it does on any
+// path, and is only a copy, or a fresh value under construction, if it is
+// one on every path
+func joinFlowStates(states []flowState) flowState {
+	joined := make(flowState)
+	for obj := range states[0] {
+		joined[obj] = varState{copied: true, fresh: true}
+	}
+	for _, state := range states {
+		for obj, st := range state {
+			cur := joined[obj]
+			cur.alias = cur.alias || st.alias
+			cur.resliced = cur.resliced || st.resliced
+			cur.frozen = cur.frozen || st.frozen
+			joined[obj] = cur
+		}
+		for obj, cur := range joined {
+			cur.copied = cur.copied && state[obj].copied
+			cur.fresh = cur.fresh && state[obj].fresh
+			joined[obj] = cur
+		}
+	}
+	for obj, st := range joined {
+		if st == (varState{}) {
+			delete(joined, obj)
+		}
+	}
+	return joined
+}
+
+// funcFlow holds the variables of one function whose alias and copy state is
+// tracked per program point: its parameters and locals, except those captured
+// by a nested closure, which may change them at any time and therefore keep
+// the flow-insensitive state computed by trackCopiesAndAliases
+type funcFlow struct {
+	tracked     map[types.Object]bool
+	constructor bool // see isConstructor
+}
+
+// nodeState is the state before a CFG node of a function
+type nodeState struct {
+	fn    *funcFlow
+	state flowState
+}
+
+// trackFlowStates runs a forward dataflow analysis over the CFG of every
+// function and function literal, recording the alias, copy and construction
+// state of its tracked variables before each node. checkMutations installs
+// these states into aliasToImmutableField, copiedVariables and constructing
+// as it walks the AST, so that `p := &im.Num; p = &local; *p = 1` and
+// `v := m["k"]; v = *imPtr; v.Num = 1` are judged by the value each
+// variable holds at the point of the write, and `c := &Config{}; c.Port = p;
+// return c` by whether c escaped before it.
+func (pc *passCollector) trackFlowStates() {
+	putLog(info, "started flow-sensitive alias tracking pass")
+
+	for _, file := range pc.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch fn := n.(type) {
+			case *ast.FuncDecl:
+				if fn.Body != nil {
+					pc.analyzeFlow(fn, fn.Body)
+				}
+			case *ast.FuncLit:
+				pc.analyzeFlow(fn, fn.Body)
+			}
+			return true
+		})
+	}
+
+	putLog(info, "finished flow-sensitive alias tracking pass")
+}
+
+func (pc *passCollector) analyzeFlow(fnNode ast.Node, body *ast.BlockStmt) {
+	fn := &funcFlow{
+		tracked:     pc.trackedVariables(fnNode),
+		constructor: pc.isConstructor(fnNode),
+	}
+	if len(fn.tracked) == 0 {
+		return
+	}
+
+	g := cfg.New(body, mayReturn)
+	preds := make([][]*cfg.Block, len(g.Blocks))
+	for _, b := range g.Blocks {
+		for _, succ := range b.Succs {
+			preds[succ.Index] = append(preds[succ.Index], b)
+		}
+	}
+
+	out := make([]flowState, len(g.Blocks))
+	for iter, changed := 0, true; changed && iter < maxFlowIterations; iter++ {
+		changed = false
+		for _, b := range g.Blocks {
+			if !b.Live {
+				continue
+			}
+
+			var in flowState
+			if b.Index == 0 {
+				in = make(flowState)
+				for _, obj := range pc.valueCopyParams(fnNode) {
+					if fn.tracked[obj] {
+						in[obj] = varState{copied: true}
+					}
+				}
+				for _, obj := range pc.readonlyParams(fnNode) {
+					if fn.tracked[obj] {
+						in[obj] = varState{alias: true}
+					}
+				}
+			} else {
+				var incoming []flowState
+				for _, pred := range preds[b.Index] {
+					if out[pred.Index] != nil {
+						incoming = append(incoming, out[pred.Index])
+					}
+				}
+				if len(incoming) == 0 {
+					continue
+				}
+				in = joinFlowStates(incoming)
+			}
+
+			state := in.clone()
+			if rng, ok := b.Stmt.(*ast.RangeStmt); ok && b.Kind == cfg.KindRangeBody {
+				pc.transferRange(fn, rng, state)
+			}
+			for _, node := range b.Nodes {
+				pc.nodeStates[node] = nodeState{fn: fn, state: state.clone()}
+				pc.transfer(fn, node, state)
+			}
+
+			if out[b.Index] == nil || !out[b.Index].equal(state) {
+				out[b.Index] = state
+				changed = true
+			}
+		}
+	}
+}
+
+// mayReturn reports whether a call may return, for CFG construction
+func mayReturn(call *ast.CallExpr) bool {
+	if ident, ok := stripParens(call.Fun).(*ast.Ident); ok && ident.Name == "panic" {
+		return false
+	}
+	return true
+}
+
+// trackedVariables returns the parameters and locals declared directly in
+// fnNode (not in a nested function literal) that no nested literal refers to
+func (pc *passCollector) trackedVariables(fnNode ast.Node) map[types.Object]bool {
+	declared := make(map[types.Object]bool)
+	captured := make(map[types.Object]bool)
+
+	ast.Inspect(fnNode, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && lit != fnNode {
+			ast.Inspect(lit.Body, func(m ast.Node) bool {
+				if ident, ok := m.(*ast.Ident); ok {
+					if obj := pc.pass.TypesInfo.Uses[ident]; obj != nil {
+						captured[obj] = true
+					}
+				}
+				return true
+			})
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok {
+			if v, ok := pc.pass.TypesInfo.Defs[ident].(*types.Var); ok && !v.IsField() {
+				declared[v] = true
+			}
+		}
+		return true
+	})
+
+	for obj := range captured {
+		delete(declared, obj)
+	}
+	return declared
+}
+
+// installFlowState writes the state of a function's tracked variables into
+// the alias, copy, construction and publication maps consulted by the
+// mutation checks
+func (pc *passCollector) installFlowState(ns nodeState) {
+	for obj := range ns.fn.tracked {
+		st := ns.state[obj]
+		if st.alias {
+			pc.aliasToImmutableField[obj] = true
+		} else {
+			delete(pc.aliasToImmutableField, obj)
+		}
+		if st.resliced {
+			pc.reslices[obj] = true
+		} else {
+			delete(pc.reslices, obj)
+		}
+		if st.copied {
+			pc.copiedVariables[obj] = true
+		} else {
+			delete(pc.copiedVariables, obj)
+		}
+		if st.fresh {
+			pc.constructing[obj] = true
+		} else {
+			delete(pc.constructing, obj)
+		}
+		pc.frozenValues[obj] = st.frozen
+	}
+}
+
+// transfer applies the effect of a CFG node on the state of tracked variables.
+// Every assignment to a tracked variable replaces its state, every value
+// the node lets escape is no longer under construction, and every
+// @frozen-after value it publishes is frozen from then on.
+func (pc *passCollector) transfer(fn *funcFlow, node ast.Node, state flowState) {
+	if fn.constructor {
+		pc.escapeUses(node, state)
+	}
+	pc.publishUses(node, func(obj types.Object) {
+		if fn.tracked[obj] {
+			st := state[obj]
+			st.frozen = true
+			setVarState(state, obj, st)
+		}
+	})
+
+	var lhs []ast.Expr
+	var rhs []ast.Expr
+
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+			return
+		}
+		lhs, rhs = n.Lhs, n.Rhs
+	case *ast.ValueSpec:
+		for _, name := range n.Names {
+			lhs = append(lhs, name)
+		}
+		rhs = n.Values
+	default:
+		return
+	}
+
+	// evaluate every right-hand side against the state before the assignment
+	pc.installFlowState(nodeState{fn: fn, state: state})
+	results := make([]varState, len(lhs))
+	if len(lhs) == len(rhs) {
+		for i := range rhs {
+			results[i] = pc.assignedState(fn, lhs[i], rhs[i])
+		}
+	} else if len(rhs) == 0 {
+		// var c Config starts out as a zero value owned by the function
+		for i := range lhs {
+			results[i] = varState{fresh: fn.constructor && pc.isConstructedVar(lhs[i])}
+		}
+	} else if len(rhs) == 1 {
+		// v, ok := m["k"] copies like v := m["k"], and b, ok := <-ch
+		// receives a value its sender published
+		results[0] = varState{copied: pc.isCopyAssignment(lhs[0], rhs[0])}
+		for i := range lhs {
+			results[i].frozen = pc.isFrozenVar(lhs[i])
+		}
+	}
+
+	for i, l := range lhs {
+		if obj := pc.trackedObject(fn, l); obj != nil {
+			setVarState(state, obj, results[i])
+		}
+	}
+}
+
+// transferRange assigns the key and value variables of a range loop at the
+// start of each iteration
+func (pc *passCollector) transferRange(fn *funcFlow, rng *ast.RangeStmt, state flowState) {
+	if rng.Tok != token.ASSIGN && rng.Tok != token.DEFINE {
+		return
+	}
+	pc.installFlowState(nodeState{fn: fn, state: state})
+	if obj := pc.trackedObject(fn, rng.Key); obj != nil {
+		setVarState(state, obj, varState{})
+	}
+	if obj := pc.trackedObject(fn, rng.Value); obj != nil {
+		alias := isReferenceType(pc.pass.TypesInfo.TypeOf(rng.Value)) &&
+			isImmutableMutationWithAliases(pc.pass, rng.X, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias)
+		setVarState(state, obj, varState{alias: alias, copied: pc.isRangeValueCopy(rng), frozen: pc.isFrozenVar(rng.Value)})
+	}
+}
+
+func setVarState(state flowState, obj types.Object, st varState) {
+	if st == (varState{}) {
+		delete(state, obj)
+	} else {
+		state[obj] = st
+	}
+}
+
+// assignedState returns the state of a variable of fn after `lhs = rhs`
+func (pc *passCollector) assignedState(fn *funcFlow, lhs ast.Expr, rhs ast.Expr) varState {
+	alias := pc.isAliasSource(rhs)
+	return varState{
+		alias:    alias,
+		resliced: alias && pc.isResliceSource(rhs),
+		copied:   pc.isCopyAssignment(lhs, rhs),
+		fresh:    fn.constructor && pc.isConstructedVar(lhs) && isFreshAllocation(pc.pass, rhs),
+		frozen:   pc.isFrozenVar(lhs) && !pc.isUnpublishedSource(rhs),
+	}
+}
+
+// isIndexCopy reports whether `lhs = rhs` copies an immutable value out of a
+// map or slice, see trackCopy
+func (pc *passCollector) isIndexCopy(lhs ast.Expr, rhs ast.Expr) bool {
+	if _, ok := stripParens(rhs).(*ast.IndexExpr); !ok {
+		return false
+	}
+	ident, ok := lhs.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj := pc.pass.TypesInfo.ObjectOf(ident)
+	if obj == nil || !isImmutableType(obj.Type(), pc.immutableTypes) {
+		return false
+	}
+	_, isPtr := obj.Type().(*types.Pointer)
+	return !isPtr
+}
+
+// trackedObject returns the tracked variable expr refers to, if any
+func (pc *passCollector) trackedObject(fn *funcFlow, expr ast.Expr) types.Object {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if obj := pc.pass.TypesInfo.ObjectOf(ident); obj != nil && fn.tracked[obj] {
+		return obj
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// defaultFreezeMethod is the method publishing a @frozen-after value when
+// the annotation does not name one
+const defaultFreezeMethod = "Freeze"
+
+// frozenInfo describes a type annotated @frozen-after: its values may be
+// assembled freely, and become immutable once published
+type frozenInfo struct {
+	typeName string // package-qualified name used in diagnostics
+	freeze   string // method whose call publishes a value
+}
+
+// frozenAfterFact is attached to every package-level type name annotated
+// @frozen-after, recording the method that publishes its values
+type frozenAfterFact struct {
+	Method string
+}
+
+func (*frozenAfterFact) AFact() {}
+
+func (f *frozenAfterFact) String() string { return "frozen after " + f.Method }
+
+// frozenAfterAnnotation returns the freeze method named by a frozen-after
+// directive in the doc comment of genDecl (// @frozen-after Seal), the
+// default method for a bare directive, and whether the declaration is
+// annotated at all
+func frozenAfterAnnotation(genDecl *ast.GenDecl, keywords []string) (string, bool) {
+	d, ok := findDirective(genDecl.Doc, directiveFrozenAfter, keywords)
+	if !ok {
+		return "", false
+	}
+	if len(d.args) > 0 && token.IsIdentifier(d.args[0]) {
+		return d.args[0], true
+	}
+	return defaultFreezeMethod, true
+}
+
+// collectFrozenTypes records the types annotated @frozen-after in genDecl
+func (pc *passCollector) collectFrozenTypes(genDecl *ast.GenDecl) {
+	method, ok := frozenAfterAnnotation(genDecl, pc.settings.FrozenAfterKeywords)
+	if !ok {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		obj, ok := pc.pass.TypesInfo.Defs[typeSpec.Name].(*types.TypeName)
+		if !ok {
+			continue
+		}
+		pc.frozenTypes[obj] = frozenInfo{typeName: qualifiedTypeName(obj), freeze: method}
+		if obj.Parent() == pc.pass.Pkg.Scope() {
+			pc.pass.ExportObjectFact(obj, &frozenAfterFact{Method: method})
+		}
+	}
+}
+
+// collectImportedFrozenTypes records the @frozen-after types of dependencies
+func (pc *passCollector) collectImportedFrozenTypes() {
+	for _, fact := range pc.pass.AllObjectFacts() {
+		frozen, ok := fact.Fact.(*frozenAfterFact)
+		if !ok {
+			continue
+		}
+		if typeName, ok := fact.Object.(*types.TypeName); ok && typeName.Pkg() != pc.pass.Pkg {
+			pc.frozenTypes[typeName] = frozenInfo{typeName: qualifiedTypeName(typeName), freeze: frozen.Method}
+		}
+	}
+}
+
+// frozenTypeOf returns the @frozen-after type typ is, or points to
+func frozenTypeOf(typ types.Type, frozenTypes map[*types.TypeName]frozenInfo) (frozenInfo, bool) {
+	if typ == nil {
+		return frozenInfo{}, false
+	}
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok {
+		return frozenInfo{}, false
+	}
+	info, ok := frozenTypes[named.Origin().Obj()]
+	return info, ok
+}
+
+// isFrozenVar reports whether expr is a variable holding a @frozen-after
+// value, or a pointer to one
+func (pc *passCollector) isFrozenVar(expr ast.Expr) bool {
+	ident, ok := stripParens(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	v, ok := pc.pass.TypesInfo.ObjectOf(ident).(*types.Var)
+	if !ok {
+		return false
+	}
+	_, frozen := frozenTypeOf(v.Type(), pc.frozenTypes)
+	return frozen
+}
+
+// publishUses calls publish for every @frozen-after variable that node
+// publishes: sends on a channel, returns, stores into a package-level
+// variable, or calls the freeze method of
+func (pc *passCollector) publishUses(node ast.Node, publish func(types.Object)) {
+	if len(pc.frozenTypes) == 0 {
+		return
+	}
+	publishAll := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			ast.Inspect(expr, func(n ast.Node) bool {
+				switch e := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.Ident:
+					if pc.isFrozenVar(e) {
+						publish(pc.pass.TypesInfo.ObjectOf(e))
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.SendStmt:
+		publishAll(n.Value)
+	case *ast.ReturnStmt:
+		publishAll(n.Results...)
+	case *ast.AssignStmt:
+		for i, lhs := range n.Lhs {
+			if isPackageLevel(pc.pass, rootIdent(lhs)) && len(n.Lhs) == len(n.Rhs) {
+				publishAll(n.Rhs[i])
+			}
+		}
+	}
+
+	// x.Freeze() anywhere in the node
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			sel, ok := stripParens(e.Fun).(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			info, ok := frozenTypeOf(pc.pass.TypesInfo.TypeOf(sel.X), pc.frozenTypes)
+			if ok && sel.Sel.Name == info.freeze {
+				if ident := rootIdent(sel.X); ident != nil && pc.isFrozenVar(ident) {
+					publish(pc.pass.TypesInfo.ObjectOf(ident))
+				}
+			}
+		}
+		return true
+	})
+}
+
+// isUnpublishedSource reports whether assigning rhs leaves a @frozen-after
+// variable holding an unpublished value: a fresh allocation, or another
+// variable (or its address) that has not been published at this point
+func (pc *passCollector) isUnpublishedSource(rhs ast.Expr) bool {
+	if isFreshAllocation(pc.pass, rhs) {
+		return true
+	}
+	rhs = stripParens(rhs)
+	if unary, ok := rhs.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		rhs = stripParens(unary.X)
+	}
+	ident, ok := rhs.(*ast.Ident)
+	return ok && !isPublishedVar(pc.pass, ident, pc.frozenValues, pc.publishedVars)
+}
+
+// trackPublishedVars computes, for variables whose flow state is not tracked
+// because closures capture them, whether they are published anywhere or
+// ever assigned a value that was published elsewhere
+func (pc *passCollector) trackPublishedVars() {
+	if len(pc.frozenTypes) == 0 {
+		return
+	}
+	publish := func(obj types.Object) {
+		pc.publishedVars[obj] = true
+	}
+	assigned := func(lhs ast.Expr, rhs ast.Expr) {
+		if pc.isFrozenVar(lhs) && (rhs == nil || !isFreshAllocation(pc.pass, rhs)) {
+			publish(pc.pass.TypesInfo.ObjectOf(stripParens(lhs).(*ast.Ident)))
+		}
+	}
+
+	for _, file := range pc.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.SendStmt, *ast.ReturnStmt, *ast.ExprStmt:
+				pc.publishUses(node, publish)
+			case *ast.AssignStmt:
+				pc.publishUses(node, publish)
+				for i, lhs := range node.Lhs {
+					if len(node.Lhs) == len(node.Rhs) {
+						assigned(lhs, node.Rhs[i])
+					} else {
+						assigned(lhs, nil)
+					}
+				}
+			case *ast.RangeStmt:
+				if node.Value != nil {
+					assigned(node.Value, nil)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// rootIdent returns the variable at the root of a selector, index or
+// dereference chain (b in b.Items[0].Name), or nil
+func rootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := stripParens(expr).(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.UnaryExpr:
+			if e.Op != token.AND {
+				return nil
+			}
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// isPackageLevel reports whether ident refers to a package-level variable
+func isPackageLevel(pass *analysis.Pass, ident *ast.Ident) bool {
+	if ident == nil {
+		return false
+	}
+	v, ok := pass.TypesInfo.ObjectOf(ident).(*types.Var)
+	return ok && v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// isPublishedVar reports whether the @frozen-after value ident holds has
+// been published at this program point. Package-level variables always
+// are; locals and parameters are judged by their flow state, or by
+// publishedVars when closures capture them.
+func isPublishedVar(pass *analysis.Pass, ident *ast.Ident, frozenValues map[types.Object]bool, publishedVars map[types.Object]bool) bool {
+	if isPackageLevel(pass, ident) {
+		return true
+	}
+	obj := pass.TypesInfo.ObjectOf(ident)
+	if frozen, tracked := frozenValues[obj]; tracked {
+		return frozen
+	}
+	return publishedVars[obj]
+}
+
+// isPublishedWrite reports whether writing expr mutates a @frozen-after
+// value that has been published. The value is the one closest to the root
+// of the selector, index or dereference chain: a variable is judged by its
+// state, anything else (call results, values loaded from fields, maps,
+// slices or channels) has been published by whoever stored it.
+func isPublishedWrite(ctx *analysisCtx, expr ast.Expr) bool {
+	if len(ctx.frozenTypes) == 0 {
+		return false
+	}
+
+	var operands []ast.Expr
+	for e := stripParens(expr); ; {
+		var x ast.Expr
+		switch node := e.(type) {
+		case *ast.SelectorExpr:
+			if _, ok := ctx.pass.TypesInfo.Selections[node]; ok {
+				x = node.X
+			}
+		case *ast.IndexExpr:
+			x = node.X
+		case *ast.StarExpr:
+			x = node.X
+		case *ast.UnaryExpr:
+			if node.Op == token.AND {
+				x = node.X
+			}
+		}
+		if x == nil {
+			break
+		}
+		e = stripParens(x)
+		operands = append(operands, e)
+	}
+	if len(operands) == 0 {
+		// the receiver or argument of a call is itself the value
+		operands = append(operands, stripParens(expr))
+	}
+
+	for i := len(operands) - 1; i >= 0; i-- {
+		if _, ok := frozenTypeOf(ctx.pass.TypesInfo.TypeOf(operands[i]), ctx.frozenTypes); !ok {
+			continue
+		}
+		if ident, ok := operands[i].(*ast.Ident); ok {
+			return isPublishedVar(ctx.pass, ident, ctx.frozenValues, ctx.publishedVars)
+		}
+		return true
+	}
+	return false
+}
+
+// frozenHelp describes a write to a published @frozen-after value
+func frozenHelp(ctx *analysisCtx, expr ast.Expr) string {
+	root := stripParens(expr)
+	for {
+		if info, ok := frozenTypeOf(ctx.pass.TypesInfo.TypeOf(root), ctx.frozenTypes); ok {
+			return fmt.Sprintf("mutating a %s after it was published (@frozen-after %s)", info.typeName, info.freeze)
+		}
+		switch e := root.(type) {
+		case *ast.SelectorExpr:
+			root = stripParens(e.X)
+		case *ast.IndexExpr:
+			root = stripParens(e.X)
+		case *ast.StarExpr:
+			root = stripParens(e.X)
+		case *ast.UnaryExpr:
+			root = stripParens(e.X)
+		default:
+			return "mutating a @frozen-after value after it was published"
+		}
+	}
+}
+
+// checkFrozen reports assignments, increments and calls in node that mutate
+// a published @frozen-after value. Writes already reported as mutations of
+// an immutable value are left to those checks.
+func checkFrozen(ctx *analysisCtx, node ast.Node) {
+	if len(ctx.frozenTypes) == 0 {
+		return
+	}
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		if n.Tok == token.DEFINE {
+			return
+		}
+		for _, lhs := range n.Lhs {
+			if _, ok := lhs.(*ast.Ident); !ok {
+				checkFrozenWrite(ctx, n.Pos(), lhs, ruleAssign)
+			}
+		}
+	case *ast.IncDecStmt:
+		checkFrozenWrite(ctx, n.Pos(), n.X, ruleIncDec)
+	case *ast.CallExpr:
+		if dst := mutatingBuiltinTarget(ctx.pass, n); dst != nil {
+			checkFrozenWrite(ctx, n.Pos(), dst, ruleBuiltin)
+			return
+		}
+		forEachMutatedArg(ctx.pass, ctx.summaries, n, func(arg ast.Expr, _ bool, fn *types.Func, what string) {
+			if isPublishedWrite(ctx, arg) && !isImmutableMutationWithAliases(ctx.pass, arg, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+				reportMutation(ctx, n.Pos(), getExpressionString(arg), arg, ruleCall,
+					fmt.Sprintf("passing a published @frozen-after value to %s, which mutates %s", funcDisplayName(fn), what))
+			}
+		})
+	}
+}
+
+// checkFrozenWrite reports a write to expr when it mutates a published
+// @frozen-after value
+func checkFrozenWrite(ctx *analysisCtx, pos token.Pos, expr ast.Expr, rule string) {
+	if isPublishedWrite(ctx, expr) && !isImmutableMutationWithAliases(ctx.pass, expr, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+		reportMutation(ctx, pos, getExpressionString(expr), expr, rule, frozenHelp(ctx, expr))
+	}
+}
@@ -4,27 +4,64 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"reflect"
+	"regexp"
 	"strings"
 
 	"golang.org/x/tools/go/analysis"
 )
 
-var Analyzer = &analysis.Analyzer{
-	Name:     "immutablecheck",
-	Doc:      "check for mutations of @immutable marked types",
-	Run:      run,
-	Requires: []*analysis.Analyzer{},
+// Analyzer is the immutablecheck analyzer with default settings
+var Analyzer = NewAnalyzer(DefaultSettings())
+
+// NewAnalyzer returns an analyzer instance bound to settings. Unset options
+// fall back to their defaults.
+func NewAnalyzer(settings Settings) *analysis.Analyzer {
+	settings = settings.withDefaults()
+	a := &analysis.Analyzer{
+		Name: "immutablecheck",
+		Doc:  "check for mutations of @immutable marked types",
+		Run: func(pass *analysis.Pass) (any, error) {
+			return run(pass, &settings)
+		},
+		Requires:   []*analysis.Analyzer{newSSABuilder(&settings)},
+		ResultType: reflect.TypeOf((*checkResult)(nil)),
+		FactTypes:  []analysis.Fact{new(immutableFact), new(immutableFieldFact), new(mutableFieldFact), new(immutableVarFact), new(readonlyMethodFact), new(pureFact), new(readonlyParamsFact), new(frozenAfterFact), new(mutatesFact), new(localReceiverFact)},
+	}
+	registerFlags(&a.Flags, &settings)
+	return a
 }
 
 func New(conf any) ([]*analysis.Analyzer, error) {
-	return []*analysis.Analyzer{Analyzer}, nil
+	settings, err := decodeSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewAnalyzers(settings), nil
 }
 
+// immutableInfo describes a type annotated as immutable, keyed by its *types.TypeName
+// so that identically named types in different packages or scopes stay distinct
 type immutableInfo struct {
-	typeName string
+	typeName string // package-qualified name used in diagnostics
 	pos      token.Pos
 }
 
+func newImmutableInfo(obj *types.TypeName) immutableInfo {
+	return immutableInfo{
+		typeName: qualifiedTypeName(obj),
+		pos:      obj.Pos(),
+	}
+}
+
+// qualifiedTypeName returns obj's name prefixed with its package path
+func qualifiedTypeName(obj *types.TypeName) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
 // check for parser errors, if they exist, skip analysis
 func isParserOk(pass *analysis.Pass) (any, error) {
 	for _, file := range pass.Files {
@@ -54,19 +91,57 @@ func isParserOk(pass *analysis.Pass) (any, error) {
 // passCollector orchestrates the multi-pass analysis for immutability checking
 type passCollector struct {
 	pass                  *analysis.Pass
-	immutableTypes        map[string]immutableInfo
-	varToTypeAlias        map[types.Object]string
+	settings              *Settings
+	immutableTypes        map[*types.TypeName]immutableInfo
+	varToTypeAlias        map[types.Object]*types.TypeName
 	copiedVariables       map[types.Object]bool
 	aliasToImmutableField map[types.Object]bool
+	reslices              map[types.Object]bool // aliases holding a re-slice of immutable storage
+	reflectValues         map[types.Object]ast.Expr
+	summaries             *summaryTable
+	ifaceValues           map[types.Object]ast.Expr
+	methodValues          map[types.Object]*ast.SelectorExpr
+	nodeStates            map[ast.Node]nodeState
+	constructing          map[types.Object]bool
+	constructorPattern    *regexp.Regexp
+	localReceivers        map[*types.Func]bool // see computeLocalReceivers
+	frozenTypes           map[*types.TypeName]frozenInfo
+	frozenValues          map[types.Object]bool
+	publishedVars         map[types.Object]bool
+	immutableVars         map[types.Object]bool
+	deepVars              map[types.Object]bool
+	varPointers           map[types.Object]types.Object
+	readonly              map[types.Object]bool
+	pureFuncs             map[*ast.FuncDecl]bool
+	suppressed            map[token.Pos]bool
 }
 
-func newPassCollector(pass *analysis.Pass) *passCollector {
+func newPassCollector(pass *analysis.Pass, settings *Settings) *passCollector {
 	return &passCollector{
 		pass:                  pass,
-		immutableTypes:        make(map[string]immutableInfo),
-		varToTypeAlias:        make(map[types.Object]string),
+		settings:              settings,
+		immutableTypes:        make(map[*types.TypeName]immutableInfo),
+		varToTypeAlias:        make(map[types.Object]*types.TypeName),
 		copiedVariables:       make(map[types.Object]bool),
 		aliasToImmutableField: make(map[types.Object]bool),
+		reslices:              make(map[types.Object]bool),
+		reflectValues:         make(map[types.Object]ast.Expr),
+		summaries:             newSummaryTable(settings),
+		ifaceValues:           make(map[types.Object]ast.Expr),
+		methodValues:          make(map[types.Object]*ast.SelectorExpr),
+		nodeStates:            make(map[ast.Node]nodeState),
+		constructing:          make(map[types.Object]bool),
+		constructorPattern:    compileConstructorPattern(settings.ConstructorPattern),
+		localReceivers:        make(map[*types.Func]bool),
+		frozenTypes:           make(map[*types.TypeName]frozenInfo),
+		frozenValues:          make(map[types.Object]bool),
+		publishedVars:         make(map[types.Object]bool),
+		immutableVars:         make(map[types.Object]bool),
+		deepVars:              make(map[types.Object]bool),
+		varPointers:           make(map[types.Object]types.Object),
+		readonly:              make(map[types.Object]bool),
+		pureFuncs:             make(map[*ast.FuncDecl]bool),
+		suppressed:            make(map[token.Pos]bool),
 	}
 }
 
@@ -80,32 +155,75 @@ func (pc *passCollector) secondPass() {
 
 func (pc *passCollector) thirdPass() {
 	pc.trackCopiesAndAliases()
+	pc.trackReflectValues()
+	pc.computeMutationSummaries()
+	pc.computeLocalReceivers()
+	pc.trackCallableValues()
+	pc.trackPublishedVars()
+	pc.trackVarPointers()
+	pc.trackFlowStates()
 }
 
 func (pc *passCollector) fourthPass() {
 	pc.checkMutations()
 }
 
-// collectImmutableTypes finds all types marked with @immutable annotation
+// collectImmutableTypes finds all types marked with @immutable annotation,
+// both in this package and in its dependencies (via exported facts), and the
+// individual struct fields and variables marked the same way, as well as
+// read-only parameters and pure functions
 func (pc *passCollector) collectImmutableTypes() {
 	putLog(info, "started collecting immutable types")
 
+	for _, typeName := range importedImmutableTypes(pc.pass) {
+		pc.immutableTypes[typeName] = newImmutableInfo(typeName)
+	}
+
+	for _, typeName := range lookupConfiguredTypes(pc.pass.Pkg, pc.settings.ImmutableTypes) {
+		pc.immutableTypes[typeName] = newImmutableInfo(typeName)
+	}
+
+	pc.collectImportedFrozenTypes()
+
 	for _, file := range pc.pass.Files {
 		ast.Inspect(file, func(n ast.Node) bool {
 			switch node := n.(type) {
 			case *ast.GenDecl:
+				if node.Tok == token.TYPE {
+					pc.collectFrozenTypes(node)
+				}
+				// single-assignment variables, package-level or local
+				if node.Tok == token.VAR {
+					pc.collectImmutableVars(node)
+				}
 				// check for type declaration with `@immutable` comment
-				if node.Tok == token.TYPE && hasImmutableComment(node, file.Comments) {
+				if node.Tok == token.TYPE && hasImmutableComment(node, pc.settings.ImmutableKeywords) {
 					for _, spec := range node.Specs {
 						if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-							typeName := typeSpec.Name.Name
-							pc.immutableTypes[typeName] = immutableInfo{
-								typeName: typeName,
+							obj, ok := pc.pass.TypesInfo.Defs[typeSpec.Name].(*types.TypeName)
+							if !ok {
+								continue
+							}
+							pc.immutableTypes[obj] = immutableInfo{
+								typeName: qualifiedTypeName(obj),
 								pos:      typeSpec.Pos(),
 							}
+							exportImmutableFact(pc.pass, obj)
 						}
 					}
 				}
+			case *ast.AssignStmt:
+				pc.collectImmutableDefine(node, file.Comments)
+			case *ast.FuncDecl:
+				pc.collectReadonlyParams(node, file.Comments)
+				pc.collectPureFunc(node)
+				pc.exportReadonlyParams(node)
+			case *ast.FuncLit:
+				pc.collectReadonlyParams(node, file.Comments)
+			case *ast.StructType:
+				// fields annotated individually, frozen inside a mutable
+				// struct or left mutable inside an immutable one
+				pc.collectFieldAnnotations(node)
 			}
 			return true
 		})
@@ -140,12 +258,7 @@ func (pc *passCollector) processValueSpec(node *ast.ValueSpec) {
 		return
 	}
 
-	ident, ok := node.Type.(*ast.Ident)
-	if !ok {
-		return
-	}
-
-	typeName := ident.Name
+	typeName := pc.typeNameOf(node.Type)
 	if _, exists := pc.immutableTypes[typeName]; exists {
 		// Associate all variables in this spec with this type name
 		for _, name := range node.Names {
@@ -167,23 +280,19 @@ func (pc *passCollector) processAssignStmt(node *ast.AssignStmt) {
 			break
 		}
 
-		var typeName string
+		var typeName *types.TypeName
 
 		// Check if RHS is a type conversion
 		if call, ok := rhs.(*ast.CallExpr); ok {
-			if ident, ok := call.Fun.(*ast.Ident); ok {
-				typeName = ident.Name
-			}
+			typeName = pc.typeNameOf(call.Fun)
 		}
 
 		// Check for composite literals like: x := AliasType{...}
 		if compLit, ok := rhs.(*ast.CompositeLit); ok {
-			if ident, ok := compLit.Type.(*ast.Ident); ok {
-				typeName = ident.Name
-			}
+			typeName = pc.typeNameOf(compLit.Type)
 		}
 
-		if typeName != "" {
+		if typeName != nil {
 			if _, exists := pc.immutableTypes[typeName]; exists {
 				if lhsIdent, ok := node.Lhs[i].(*ast.Ident); ok {
 					if obj := pc.pass.TypesInfo.ObjectOf(lhsIdent); obj != nil {
@@ -195,18 +304,54 @@ func (pc *passCollector) processAssignStmt(node *ast.AssignStmt) {
 	}
 }
 
-// trackCopiesAndAliases identifies variables that are copies from map/slice access
-// and tracks pointer aliases to immutable fields
+// typeNameOf resolves a type expression written as T or pkg.T to the type name
+// it refers to, or nil if expr does not name a type
+func (pc *passCollector) typeNameOf(expr ast.Expr) *types.TypeName {
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return nil
+	}
+	typeName, _ := pc.pass.TypesInfo.Uses[ident].(*types.TypeName)
+	return typeName
+}
+
+// trackCopiesAndAliases records copies of immutable values (see
+// isCopyAssignment) and aliases of immutable storage: pointers into it and
+// slices, maps and pointers loaded from it. Aliases of aliases are found by
+// iterating to a fixed point.
 func (pc *passCollector) trackCopiesAndAliases() {
 	putLog(info, "started tracking copies and aliases pass")
 
-	for _, file := range pc.pass.Files {
-		ast.Inspect(file, func(n ast.Node) bool {
-			if assign, ok := n.(*ast.AssignStmt); ok {
-				pc.processAssignmentForCopiesAndAliases(assign)
-			}
-			return true
-		})
+	for {
+		aliases := len(pc.aliasToImmutableField)
+		for _, file := range pc.pass.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch node := n.(type) {
+				case *ast.AssignStmt:
+					pc.processAssignmentForCopiesAndAliases(node)
+				case *ast.ValueSpec:
+					pc.processValueSpecForAliases(node)
+				case *ast.RangeStmt:
+					pc.processRangeForAliases(node)
+				case *ast.FuncDecl, *ast.FuncLit:
+					for _, obj := range pc.valueCopyParams(node) {
+						pc.copiedVariables[obj] = true
+					}
+					for _, obj := range pc.readonlyParams(node) {
+						pc.aliasToImmutableField[obj] = true
+					}
+				}
+				return true
+			})
+		}
+		if len(pc.aliasToImmutableField) == aliases {
+			break
+		}
 	}
 
 	putLog(info, "finished tracking copies and aliases pass")
@@ -219,44 +364,92 @@ func (pc *passCollector) processAssignmentForCopiesAndAliases(assign *ast.Assign
 	}
 
 	for i, rhs := range assign.Rhs {
-		// Track copies from index expressions
-		if _, ok := rhs.(*ast.IndexExpr); ok {
-			pc.trackCopyFromIndex(assign, i)
-		}
+		// Track copies from index expressions, and value copies with copy semantics
+		pc.trackCopy(assign, i)
 
-		// Track aliases to immutable fields
-		if pc.isAddrOfImmutableField(rhs) {
+		if pc.isAliasSource(rhs) {
 			pc.markAlias(assign.Lhs, i)
-			continue
+			if ident, ok := assign.Lhs[i].(*ast.Ident); ok && pc.isResliceSource(rhs) {
+				if obj := pc.pass.TypesInfo.ObjectOf(ident); obj != nil {
+					pc.reslices[obj] = true
+				}
+			}
 		}
+	}
+}
 
-		// Track aliases with type conversion: p := (*int)(&im.Num)
-		if call, ok := rhs.(*ast.CallExpr); ok {
-			if len(call.Args) == 1 && pc.isAddrOfImmutableField(call.Args[0]) {
-				pc.markAlias(assign.Lhs, i)
+// processValueSpecForAliases tracks aliases declared with var: var s = im.Arr
+func (pc *passCollector) processValueSpecForAliases(spec *ast.ValueSpec) {
+	if len(spec.Names) != len(spec.Values) {
+		return
+	}
+	for i, value := range spec.Values {
+		if pc.isAliasSource(value) {
+			if obj := pc.pass.TypesInfo.Defs[spec.Names[i]]; obj != nil {
+				pc.aliasToImmutableField[obj] = true
+				pc.reslices[obj] = pc.reslices[obj] || pc.isResliceSource(value)
 			}
 		}
 	}
 }
 
-// trackCopyFromIndex marks variables assigned from index expressions as copies
-func (pc *passCollector) trackCopyFromIndex(assign *ast.AssignStmt, i int) {
-	if i >= len(assign.Lhs) {
+// processRangeForAliases tracks range value variables sharing storage with
+// the elements of an immutable container: for _, row := range im.Two, and
+// with copy semantics those holding copies of immutable elements
+func (pc *passCollector) processRangeForAliases(rng *ast.RangeStmt) {
+	if pc.isRangeValueCopy(rng) {
+		pc.copiedVariables[pc.pass.TypesInfo.ObjectOf(rng.Value.(*ast.Ident))] = true
+	}
+	if rng.Value == nil || !isReferenceType(pc.pass.TypesInfo.TypeOf(rng.Value)) {
 		return
 	}
-
-	ident, ok := assign.Lhs[i].(*ast.Ident)
-	if !ok {
+	if !isImmutableMutationWithAliases(pc.pass, rng.X, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias) {
 		return
 	}
+	pc.markAlias([]ast.Expr{rng.Value}, 0)
+}
 
-	obj := pc.pass.TypesInfo.ObjectOf(ident)
-	if obj == nil || !isImmutableType(obj.Type(), pc.immutableTypes) {
-		return
+// isAliasSource reports whether a variable assigned rhs shares storage with an
+// immutable value. This covers pointers into immutable storage, including
+// chains of unsafe conversions (p := (*int)(unsafe.Pointer(&im.Num))), and
+// slices, maps and pointers loaded from immutable values or their aliases
+// (s := im.Arr, row := im.Two[0], t := s[1:]). Conversions such as
+// map[string]int(imMap) are an explicit opt-out and do not alias.
+func (pc *passCollector) isAliasSource(rhs ast.Expr) bool {
+	if derivesFromImmutableStorage(pc.pass, rhs, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias) {
+		return true
+	}
+
+	rhs = stripParens(rhs)
+	if !isReferenceType(pc.pass.TypesInfo.TypeOf(rhs)) {
+		return false
+	}
+	switch rhs.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.SliceExpr, *ast.StarExpr:
+		return isImmutableMutationWithAliases(pc.pass, rhs, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias)
+	}
+	return false
+}
+
+// isResliceSource reports whether rhs, which aliases immutable storage, is a
+// re-slice of it (s := im.Arr[:1]) or a variable holding one. Appending to
+// such a slice writes into elements the immutable value still sees.
+func (pc *passCollector) isResliceSource(rhs ast.Expr) bool {
+	switch r := stripParens(rhs).(type) {
+	case *ast.SliceExpr:
+		return hasSpareCapacity(pc.pass, r)
+	case *ast.Ident:
+		return pc.reslices[pc.pass.TypesInfo.ObjectOf(r)]
 	}
+	return false
+}
 
-	// check if not a pointer -> then it's a copy
-	if _, isPtr := obj.Type().(*types.Pointer); !isPtr {
+// trackCopy marks variables assigned a copy of an immutable value, see isCopyAssignment
+func (pc *passCollector) trackCopy(assign *ast.AssignStmt, i int) {
+	if i >= len(assign.Lhs) || !pc.isCopyAssignment(assign.Lhs[i], assign.Rhs[i]) {
+		return
+	}
+	if obj := pc.pass.TypesInfo.ObjectOf(assign.Lhs[i].(*ast.Ident)); obj != nil {
 		pc.copiedVariables[obj] = true
 	}
 }
@@ -277,49 +470,69 @@ func (pc *passCollector) markAlias(lhs []ast.Expr, lhsIdx int) {
 	}
 }
 
-// isAddrOfImmutableField checks if expr is &selector-to-immutable, stripping parens
-func (pc *passCollector) isAddrOfImmutableField(expr ast.Expr) bool {
-	// strip parentheses
-	if paren, ok := expr.(*ast.ParenExpr); ok {
-		return pc.isAddrOfImmutableField(paren.X)
-	}
-
-	// check for &selector
-	unary, ok := expr.(*ast.UnaryExpr)
-	if !ok || unary.Op != token.AND {
-		return false
-	}
-
-	sel, ok := unary.X.(*ast.SelectorExpr)
-	if !ok {
-		return false
-	}
-
-	return getImmutableTypeName(pc.pass, sel, pc.immutableTypes) != ""
-}
-
 // checkMutations performs the final pass to detect and report immutable violations
 func (pc *passCollector) checkMutations() {
 	putLog(info, "started mutation checking pass")
 
 	ctx := &analysisCtx{
 		pass:                  pc.pass,
+		settings:              pc.settings,
 		immutableTypes:        pc.immutableTypes,
 		copiedVariables:       pc.copiedVariables,
 		aliasToImmutableField: pc.aliasToImmutableField,
+		reslices:              pc.reslices,
 		varToTypeAlias:        pc.varToTypeAlias,
+		reflectValues:         pc.reflectValues,
+		summaries:             pc.summaries,
+		ifaceValues:           pc.ifaceValues,
+		methodValues:          pc.methodValues,
+		constructing:          pc.constructing,
+		frozenTypes:           pc.frozenTypes,
+		frozenValues:          pc.frozenValues,
+		publishedVars:         pc.publishedVars,
+		immutableVars:         pc.immutableVars,
+		deepVars:              pc.deepVars,
+		varPointers:           pc.varPointers,
+		readonly:              pc.readonly,
+		suppressed:            pc.suppressed,
 		commentGroups:         nil,
 	}
 
 	for _, file := range pc.pass.Files {
+		if pc.settings.ExemptTests && isTestFile(pc.pass, file) {
+			continue
+		}
 		ast.Inspect(file, func(n ast.Node) bool {
+			// judge aliases and copies by their state at this program point
+			if ns, ok := pc.nodeStates[n]; ok {
+				pc.installFlowState(ns)
+			}
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				ctx.pure = pc.pureFuncs[decl]
+			}
+			ctx.node = n
+			ctx.commentGroups = file.Comments
+			checkPure(ctx, n)
+			checkReadonlyFlow(ctx, n)
 			switch node := n.(type) {
 			case *ast.AssignStmt:
 				ctx.commentGroups = file.Comments
 				checkAssignmentWithCopiesAndAliases(ctx, node)
+				checkFrozen(ctx, node)
+				checkImmutableVars(ctx, node)
 			case *ast.IncDecStmt:
 				ctx.commentGroups = file.Comments
 				checkIncDecWithCopiesAndAliases(ctx, node)
+				checkFrozen(ctx, node)
+				checkImmutableVars(ctx, node)
+			case *ast.CallExpr:
+				ctx.commentGroups = file.Comments
+				checkCall(ctx, node)
+				checkFrozen(ctx, node)
+				checkImmutableVars(ctx, node)
+			case *ast.RangeStmt:
+				ctx.commentGroups = file.Comments
+				checkImmutableVars(ctx, node)
 			}
 			return true
 		})
@@ -328,63 +541,57 @@ func (pc *passCollector) checkMutations() {
 	putLog(info, "finished mutation checking pass")
 }
 
-func run(pass *analysis.Pass) (any, error) {
+// isTestFile reports whether file is a _test.go file
+func isTestFile(pass *analysis.Pass, file *ast.File) bool {
+	return strings.HasSuffix(pass.Fset.Position(file.Pos()).Filename, "_test.go")
+}
+
+func run(pass *analysis.Pass, settings *Settings) (any, error) {
 	putLog(info, "=====================================")
 
 	if ok, _ := isParserOk(pass); !ok.(bool) {
 		putLog(info, "immutablecheck: analysis skipped due to errors in package")
-		return nil, nil
+		return &checkResult{settings: settings}, nil
 	}
 
 	// Create pass collector and run all analysis phases
-	collector := newPassCollector(pass)
+	collector := newPassCollector(pass, settings)
 	collector.firstPass()
 	collector.secondPass()
 	collector.thirdPass()
-	collector.fourthPass()
+	if settings.Engine == engineSSA {
+		collector.checkMutationsSSA()
+	} else {
+		collector.fourthPass()
+	}
 
-	return nil, nil
+	return &checkResult{settings: settings, immutableTypes: collector.immutableTypes, suppressed: collector.suppressed}, nil
 }
 
-func hasImmutableComment(genDecl *ast.GenDecl, _ []*ast.CommentGroup) bool {
-	if genDecl.Doc != nil {
-		for _, comment := range genDecl.Doc.List {
-			text := strings.TrimSpace(comment.Text)
-			if strings.Contains(text, "@immutable") {
-				return true
-			}
-		}
-	}
-	return false
+// checkResult is the result of the analyzer, consumed by the annotation
+// checks: the settings it ran with, the immutable types it found, and the
+// lines on which an @allow-mutate directive suppressed a report, keyed by
+// the start of the line. suppressed is nil when the package was not analyzed.
+type checkResult struct {
+	settings       *Settings
+	immutableTypes map[*types.TypeName]immutableInfo
+	suppressed     map[token.Pos]bool
 }
 
-// hasAllowMutateComment checks if a statement has an @allow-mutate directive
-// The directive MUST be an inline comment directly after the statement on the same line.
-// Format: x = "value" //@allow-mutate  OR  x = "value" // @allow-mutate
-// Comments on lines above or below the statement are NOT supported.
-// ^^^ this just causes a lot of problems with how go AST groups together comments in a CommentGroup
-func hasAllowMutateComment(pass *analysis.Pass, pos token.Pos, commentGroups []*ast.CommentGroup) bool {
-	stmtPosition := pass.Fset.Position(pos)
-
-	for _, cg := range commentGroups {
-		for _, comment := range cg.List {
-			commentPos := pass.Fset.Position(comment.Pos())
-			text := strings.TrimSpace(comment.Text)
+func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map[*types.TypeName]immutableInfo) *types.TypeName {
+	// for address-of expressions (e.g., &im.Num), check the operand
+	if unary, ok := stripParens(expr).(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		return getImmutableTypeName(pass, unary.X, immutableTypes)
+	}
 
-			// Check if this specific comment contains @allow-mutate
-			// ONLY allow inline comments on the exact same line as the statement
-			if strings.Contains(text, "@allow-mutate") && commentPos.Line == stmtPosition.Line {
-				return true
-			}
-		}
+	// a re-slice (e.g., im.Arr[1:]) shares storage with the sliced operand
+	if slice, ok := stripParens(expr).(*ast.SliceExpr); ok {
+		return getImmutableTypeName(pass, slice.X, immutableTypes)
 	}
-	return false
-}
 
-func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map[string]immutableInfo) string {
 	typ := pass.TypesInfo.TypeOf(expr)
 	if typ == nil {
-		return ""
+		return nil
 	}
 
 	// for index expressions (e.g., arr[0], map["key"]), check the container
@@ -396,14 +603,14 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 				baseObj := pass.TypesInfo.ObjectOf(baseIdent)
 				if baseObj != nil {
 					baseType := baseObj.Type()
-					if immutableName := getTypeNameFromTypeRecursive(baseType, immutableTypes); immutableName != "" {
+					if immutableName := getTypeNameFromTypeRecursive(baseType, immutableTypes); immutableName != nil {
 						return immutableName
 					}
 				}
 			}
 			// also check the selector's type
 			if selType := pass.TypesInfo.TypeOf(sel.X); selType != nil {
-				if immutableName := getTypeNameFromTypeRecursive(selType, immutableTypes); immutableName != "" {
+				if immutableName := getTypeNameFromTypeRecursive(selType, immutableTypes); immutableName != nil {
 					return immutableName
 				}
 			}
@@ -411,7 +618,7 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 
 		// check the element type
 		if elemType := pass.TypesInfo.TypeOf(idx); elemType != nil {
-			if immutableName := getTypeNameFromTypeRecursive(elemType, immutableTypes); immutableName != "" {
+			if immutableName := getTypeNameFromTypeRecursive(elemType, immutableTypes); immutableName != nil {
 				return immutableName
 			}
 		}
@@ -421,15 +628,15 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 			// for slices/arrays/maps, we need to get the element type
 			switch t := containerType.(type) {
 			case *types.Slice:
-				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != "" {
+				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != nil {
 					return immutableName
 				}
 			case *types.Array:
-				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != "" {
+				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != nil {
 					return immutableName
 				}
 			case *types.Map:
-				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != "" {
+				if immutableName := getTypeNameFromTypeRecursive(t.Elem(), immutableTypes); immutableName != nil {
 					return immutableName
 				}
 			}
@@ -444,7 +651,7 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 		// first check the parent (X) type
 		parentType := pass.TypesInfo.TypeOf(sel.X)
 		if parentType != nil {
-			if immutableName := getTypeNameFromTypeRecursive(parentType, immutableTypes); immutableName != "" {
+			if immutableName := getTypeNameFromTypeRecursive(parentType, immutableTypes); immutableName != nil {
 				return immutableName
 			}
 		}
@@ -455,9 +662,9 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 		typ = ptr.Elem()
 	}
 
-	// vheck if it's a named type
+	// check if it's a named type
 	if named, ok := typ.(*types.Named); ok {
-		typeName := named.Obj().Name()
+		typeName := named.Origin().Obj()
 		if _, exists := immutableTypes[typeName]; exists {
 			return typeName
 		}
@@ -479,50 +686,88 @@ func getImmutableTypeName(pass *analysis.Pass, expr ast.Expr, immutableTypes map
 	return getTypeNameFromTypeRecursive(typ, immutableTypes)
 }
 
-func getTypeNameFromType(typ types.Type) string {
+// getTypeNameFromType returns the declared type name of typ (after removing
+// pointer indirection), or nil for unnamed types
+func getTypeNameFromType(typ types.Type) *types.TypeName {
 	// remove pointer indirection
 	if ptr, ok := typ.(*types.Pointer); ok {
 		typ = ptr.Elem()
 	}
 
-	if named, ok := typ.(*types.Named); ok {
-		return named.Obj().Name()
+	switch t := typ.(type) {
+	case *types.Named:
+		return t.Origin().Obj()
+	case *types.Alias:
+		return t.Obj()
 	}
 
-	return ""
+	return nil
 }
 
-func getTypeNameFromTypeRecursive(typ types.Type, immutableTypes map[string]immutableInfo) string {
+func getTypeNameFromTypeRecursive(typ types.Type, immutableTypes map[*types.TypeName]immutableInfo) *types.TypeName {
 	// remove pointer indirection
 	if ptr, ok := typ.(*types.Pointer); ok {
 		typ = ptr.Elem()
 	}
 
+	// follow alias chains, stopping at the first alias marked @immutable
+	for {
+		alias, ok := typ.(*types.Alias)
+		if !ok {
+			break
+		}
+		if _, exists := immutableTypes[alias.Obj()]; exists {
+			return alias.Obj()
+		}
+		typ = alias.Rhs()
+	}
+
 	if named, ok := typ.(*types.Named); ok {
-		typeName := named.Obj().Name()
+		typeName := named.Origin().Obj()
 		if _, exists := immutableTypes[typeName]; exists {
 			return typeName
 		}
 	}
 
-	return ""
+	return nil
 }
 
 type analysisCtx struct {
 	pass                  *analysis.Pass
-	immutableTypes        map[string]immutableInfo
+	settings              *Settings
+	immutableTypes        map[*types.TypeName]immutableInfo
 	copiedVariables       map[types.Object]bool
 	aliasToImmutableField map[types.Object]bool
-	varToTypeAlias        map[types.Object]string
+	reslices              map[types.Object]bool
+	varToTypeAlias        map[types.Object]*types.TypeName
+	reflectValues         map[types.Object]ast.Expr
+	summaries             *summaryTable
+	ifaceValues           map[types.Object]ast.Expr
+	methodValues          map[types.Object]*ast.SelectorExpr
+	constructing          map[types.Object]bool
+	frozenTypes           map[*types.TypeName]frozenInfo
+	frozenValues          map[types.Object]bool
+	publishedVars         map[types.Object]bool
+	immutableVars         map[types.Object]bool
+	deepVars              map[types.Object]bool
+	varPointers           map[types.Object]types.Object
+	readonly              map[types.Object]bool
+	suppressed            map[token.Pos]bool // lines on which @allow-mutate suppressed a report
+	pure                  bool               // inside the body of a @pure function
+	node                  ast.Node           // the node being checked; @allow-mutate on its line suppresses its reports
 	commentGroups         []*ast.CommentGroup
 }
 
-func checkAssignmentWithCopiesAndAliases(ctx *analysisCtx, stmt *ast.AssignStmt) {
-	// Check if this statement has an @allow-mutate directive
-	if hasAllowMutateComment(ctx.pass, stmt.Pos(), ctx.commentGroups) {
-		return // Skip this mutation check
-	}
+// checkCall reports calls that mutate immutable values without an assignment
+func checkCall(ctx *analysisCtx, call *ast.CallExpr) {
+	checkBuiltinCall(ctx, call)
+	checkReflectCall(ctx, call)
+	checkUnsafePointer(ctx, call)
+	checkMutatingCall(ctx, call)
+	checkReadonlyCall(ctx, call)
+}
 
+func checkAssignmentWithCopiesAndAliases(ctx *analysisCtx, stmt *ast.AssignStmt) {
 	// skip variable declarations (:= token)
 	// we only care about mutations, not initial assignments
 	// also like if initial assignments were not allowed then like how do I even code?
@@ -533,79 +778,78 @@ func checkAssignmentWithCopiesAndAliases(ctx *analysisCtx, stmt *ast.AssignStmt)
 	for i, lhs := range stmt.Lhs {
 		// check if LHS is just an identifier (simple variable assignment like: x = value)
 		if ident, ok := lhs.(*ast.Ident); ok {
-			// This is a simple variable assignment, not a field mutation
-			// We need to distinguish:
-			// 1. im = Immtbl{} (reassigning whole immutable value - should CATCH)
-			// 2. imPtr = &im (assigning pointer - should NOT catch, it's read-only)
-			// 3. val = mapOfImmutables["key"] (assigning copy - should also NOT catch)
-			// 4. imPtr, i = multi() where imPtr is *Imm (multi-value with pointer - should NOT catch)
-			// 5. t, y = m() where t is im value type (multi-value with value - should CATCH)
-			if isImmutableVariable(ctx.pass, ident, ctx.immutableTypes, ctx.varToTypeAlias) {
-				// Check if the variable is a pointer type
-				// If it's a pointer to an immutable type, reassigning the pointer is OK
-				// Only catch reassignment of immutable VALUES
-				obj := ctx.pass.TypesInfo.ObjectOf(ident)
-				if obj != nil {
-					if _, isPtr := obj.Type().(*types.Pointer); isPtr {
-						// This is reassigning a pointer variable - allow it
-						// This handles both single and multi-value assignments
-						continue
-					}
-				}
-
-				// check RHS - if it's just taking address, don't flag (read-only operation)
-				if i < len(stmt.Rhs) {
-					rhs := stmt.Rhs[i]
-					// allow pointer assignments like: result = &im
-					if unary, ok := rhs.(*ast.UnaryExpr); ok && unary.Op == token.AND {
-						continue
-					}
-				}
-
-				// this is reassigning the whole immutable struct - flag it
-				reportMutation(ctx.pass, stmt.Pos(), ident.Name, lhs, ctx.immutableTypes, "reassigning whole immutable struct")
-			}
+			checkReassignment(ctx, stmt, i, ident)
 			continue
 		}
 
-		// check if LHS is a selector (field access like im.Num or val.Num)
-		if sel, ok := lhs.(*ast.SelectorExpr); ok {
-			// check if the base is a copied variable
-			if base, ok := sel.X.(*ast.Ident); ok {
-				baseObj := ctx.pass.TypesInfo.ObjectOf(base)
-				if baseObj != nil && ctx.copiedVariables[baseObj] {
-					// this is mutating a copy - skip it
-					continue
-				}
-			}
+		// check if LHS writes a copied variable (val.Num), or a value its
+		// constructor is still initialising (c.Port) - skip it
+		if isCopyWrite(ctx, lhs) || isUnderConstruction(ctx, lhs) {
+			continue
 		}
 
 		// for all other LHS patterns, check if it's an immutable mutation
 		if isImmutableMutationWithAliases(ctx.pass, lhs, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
-			reportMutation(ctx.pass, stmt.Pos(), getExpressionString(lhs), lhs, ctx.immutableTypes, "mutating immutable field in assignment")
+			reportMutation(ctx, stmt.Pos(), getExpressionString(lhs), lhs, ruleAssign, "mutating immutable field in assignment")
 		}
 	}
 }
 
-func checkIncDecWithCopiesAndAliases(ctx *analysisCtx, stmt *ast.IncDecStmt) {
-	// Check if this statement has an @allow-mutate directive
-	if hasAllowMutateComment(ctx.pass, stmt.Pos(), ctx.commentGroups) {
-		return // Skip this mutation check
+// checkReassignment reports `ident = ...` at index i of stmt when ident is a
+// variable holding an immutable value
+func checkReassignment(ctx *analysisCtx, stmt *ast.AssignStmt, i int, ident *ast.Ident) {
+	// This is a simple variable assignment, not a field mutation
+	// We need to distinguish:
+	// 1. im = Immtbl{} (reassigning whole immutable value - should CATCH)
+	// 2. imPtr = &im (assigning pointer - should NOT catch, it's read-only)
+	// 3. val = mapOfImmutables["key"] (assigning copy - should also NOT catch)
+	// 4. imPtr, i = multi() where imPtr is *Imm (multi-value with pointer - should NOT catch)
+	// 5. t, y = m() where t is im value type (multi-value with value - should CATCH)
+	if !isImmutableVariable(ctx.pass, ident, ctx.immutableTypes, ctx.varToTypeAlias) {
+		return
 	}
 
-	// check if we're incrementing/decrementing a field of a copied variable
-	if sel, ok := stmt.X.(*ast.SelectorExpr); ok {
-		if base, ok := sel.X.(*ast.Ident); ok {
-			baseObj := ctx.pass.TypesInfo.ObjectOf(base)
-			if baseObj != nil && ctx.copiedVariables[baseObj] {
-				// this is mutating a copy - skip it
-				return
-			}
+	// Check if the variable is a pointer type
+	// If it's a pointer to an immutable type, reassigning the pointer is OK
+	// Only catch reassignment of immutable VALUES
+	obj := ctx.pass.TypesInfo.ObjectOf(ident)
+	if obj != nil {
+		if _, isPtr := obj.Type().(*types.Pointer); isPtr {
+			// This is reassigning a pointer variable - allow it
+			// This handles both single and multi-value assignments
+			return
 		}
 	}
 
+	// replacing a private copy leaves the original untouched, and a
+	// constructor may still replace a value nobody else has seen
+	if isCopyReassignment(ctx, ident) || isUnderConstruction(ctx, ident) {
+		return
+	}
+
+	// check RHS - if it's just taking address, don't flag (read-only operation)
+	if i < len(stmt.Rhs) {
+		rhs := stmt.Rhs[i]
+		// allow pointer assignments like: result = &im
+		if unary, ok := rhs.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			return
+		}
+	}
+
+	// this is reassigning the whole immutable struct - flag it
+	reportMutation(ctx, stmt.Pos(), ident.Name, ident, ruleReassign, "reassigning whole immutable struct")
+}
+
+func checkIncDecWithCopiesAndAliases(ctx *analysisCtx, stmt *ast.IncDecStmt) {
+	// check if we're incrementing/decrementing a field of a copied variable,
+	// or of a value under construction
+	if isCopyWrite(ctx, stmt.X) || isUnderConstruction(ctx, stmt.X) {
+		// this is mutating a copy - skip it
+		return
+	}
+
 	if isImmutableMutationWithAliases(ctx.pass, stmt.X, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
-		reportMutation(ctx.pass, stmt.Pos(), getExpressionString(stmt.X), stmt.X, ctx.immutableTypes, "incrementing/decrementing immutable field")
+		reportMutation(ctx, stmt.Pos(), getExpressionString(stmt.X), stmt.X, ruleIncDec, "incrementing/decrementing immutable field")
 	}
 }
 
@@ -620,7 +864,7 @@ func stripParens(expr ast.Expr) ast.Expr {
 	}
 }
 
-func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutableTypes map[string]immutableInfo, aliasToImmutableField map[types.Object]bool, varToTypeAlias map[types.Object]string) bool {
+func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutableTypes map[*types.TypeName]immutableInfo, aliasToImmutableField map[types.Object]bool, varToTypeAlias map[types.Object]*types.TypeName) bool {
 	// Strip all parentheses before checking
 	expr = stripParens(expr)
 
@@ -629,6 +873,15 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 		// Check if we're accessing a field of an immutable struct
 		// Need to handle both direct access (im.Field) and nested access (outer.Inner.Field)
 
+		// fields exempt from immutability: @mutable, sync and sync/atomic types
+		if selectsMutableField(pass, e) {
+			return false
+		}
+		// a field annotated @immutable inside an otherwise mutable struct
+		if selectsImmutableField(pass, e) {
+			return true
+		}
+
 		// Strip parens from the base expression
 		x := stripParens(e.X)
 
@@ -637,6 +890,10 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 			if isImmutableVariable(pass, ident, immutableTypes, varToTypeAlias) {
 				return true
 			}
+			// or aliases immutable storage: c := im.Ptr; c.Value = 1
+			if obj := pass.TypesInfo.ObjectOf(ident); obj != nil && aliasToImmutableField[obj] {
+				return true
+			}
 			// Also check if this is accessing a field from an embedded immutable type
 			baseType := pass.TypesInfo.TypeOf(ident)
 			if baseType != nil {
@@ -666,12 +923,16 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 				return true
 			}
 			return isImmutableMutationWithAliases(pass, x, immutableTypes, aliasToImmutableField, varToTypeAlias)
-		} else if _, ok := x.(*ast.CallExpr); ok {
+		} else if call, ok := x.(*ast.CallExpr); ok {
 			// Handle mutations like: getImmutable().Num
 			returnType := pass.TypesInfo.TypeOf(x)
 			if returnType != nil && isImmutableType(returnType, immutableTypes) {
 				return true
 			}
+			// and through reinterpreted pointers: (*T)(unsafe.Pointer(&im)).Field
+			if derivesFromImmutableStorage(pass, call, immutableTypes, aliasToImmutableField, varToTypeAlias) {
+				return true
+			}
 		} else if _, ok := x.(*ast.StarExpr); ok {
 			// Handle mutations like: (*ptr).Num or dereferenced pointers
 			derefType := pass.TypesInfo.TypeOf(x)
@@ -699,6 +960,12 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 		if indexedType != nil && isImmutableType(indexedType, immutableTypes) {
 			return true
 		}
+		// indexing a slice that aliases immutable storage: s := unsafe.Slice(&im.Arr[0], n)
+		if ident, ok := stripParens(e.X).(*ast.Ident); ok {
+			if obj := pass.TypesInfo.ObjectOf(ident); obj != nil && aliasToImmutableField[obj] {
+				return true
+			}
+		}
 		// Also check the container itself (strip parens first)
 		return isImmutableMutationWithAliases(pass, stripParens(e.X), immutableTypes, aliasToImmutableField, varToTypeAlias)
 
@@ -720,9 +987,17 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 				}
 			}
 		}
+		// writes through converted pointers: *(*int)(unsafe.Pointer(&im.Num)) = 1
+		if derivesFromImmutableStorage(pass, x, immutableTypes, aliasToImmutableField, varToTypeAlias) {
+			return true
+		}
 		// Also recursively check the pointer expression
 		return isImmutableMutationWithAliases(pass, x, immutableTypes, aliasToImmutableField, varToTypeAlias)
 
+	case *ast.SliceExpr:
+		// Re-slicing shares the backing array: im.Arr[1:] aliases im.Arr
+		return isImmutableMutationWithAliases(pass, stripParens(e.X), immutableTypes, aliasToImmutableField, varToTypeAlias)
+
 	case *ast.ParenExpr:
 		// Should not reach here due to stripParens at entry, but handle anyway
 		return isImmutableMutationWithAliases(pass, e.X, immutableTypes, aliasToImmutableField, varToTypeAlias)
@@ -744,7 +1019,10 @@ func isImmutableMutationWithAliases(pass *analysis.Pass, expr ast.Expr, immutabl
 		}
 
 	case *ast.Ident:
-		// direct mutation of immutable variable
+		// direct mutation of immutable variable, or of storage it aliases
+		if obj := pass.TypesInfo.ObjectOf(e); obj != nil && aliasToImmutableField[obj] {
+			return true
+		}
 		return isImmutableVariable(pass, e, immutableTypes, varToTypeAlias)
 	}
 	return false
@@ -770,7 +1048,7 @@ func getStructType(typ types.Type) (*types.Struct, bool) {
 	return nil, false
 }
 
-func isFieldFromEmbeddedImmutable(structType *types.Struct, fieldName string, immutableTypes map[string]immutableInfo) bool {
+func isFieldFromEmbeddedImmutable(structType *types.Struct, fieldName string, immutableTypes map[*types.TypeName]immutableInfo) bool {
 	for i := 0; i < structType.NumFields(); i++ {
 		field := structType.Field(i)
 		if field.Embedded() {
@@ -790,7 +1068,7 @@ func isFieldFromEmbeddedImmutable(structType *types.Struct, fieldName string, im
 	return false
 }
 
-func isImmutableVariable(pass *analysis.Pass, ident *ast.Ident, immutableTypes map[string]immutableInfo, varToTypeAlias map[types.Object]string) bool {
+func isImmutableVariable(pass *analysis.Pass, ident *ast.Ident, immutableTypes map[*types.TypeName]immutableInfo, varToTypeAlias map[types.Object]*types.TypeName) bool {
 	obj := pass.TypesInfo.ObjectOf(ident)
 	if obj == nil {
 		return false
@@ -809,8 +1087,7 @@ func isImmutableVariable(pass *analysis.Pass, ident *ast.Ident, immutableTypes m
 	if varObj, ok := obj.(*types.Var); ok {
 		// Get the type name as it appears in the source
 		if named, ok := varObj.Type().(*types.Named); ok {
-			typeName := named.Obj().Name()
-			if _, exists := immutableTypes[typeName]; exists {
+			if _, exists := immutableTypes[named.Origin().Obj()]; exists {
 				return true
 			}
 		}
@@ -820,7 +1097,7 @@ func isImmutableVariable(pass *analysis.Pass, ident *ast.Ident, immutableTypes m
 	return isImmutableType(typ, immutableTypes)
 }
 
-func isImmutableType(typ types.Type, immutableTypes map[string]immutableInfo) bool {
+func isImmutableType(typ types.Type, immutableTypes map[*types.TypeName]immutableInfo) bool {
 	// remove pointer indirection
 	if ptr, ok := typ.(*types.Pointer); ok {
 		typ = ptr.Elem()
@@ -830,7 +1107,11 @@ func isImmutableType(typ types.Type, immutableTypes map[string]immutableInfo) bo
 	// For type aliases like: type Alias = Immtbl
 	// The type will be *types.Alias, and we need to resolve it to the actual type
 	// Type aliases in go are "transparent", so we need to get the Rhs type
-	if alias, ok := typ.(interface{ Rhs() types.Type }); ok {
+	if alias, ok := typ.(*types.Alias); ok {
+		// the alias itself may carry the annotation: type AliasMap = map[string]int
+		if _, exists := immutableTypes[alias.Obj()]; exists {
+			return true
+		}
 		// get the right-hand side of the alias (the actual type)
 		actualType := alias.Rhs()
 		return isImmutableType(actualType, immutableTypes)
@@ -838,7 +1119,7 @@ func isImmutableType(typ types.Type, immutableTypes map[string]immutableInfo) bo
 
 	// check if it's a named type
 	if named, ok := typ.(*types.Named); ok {
-		typeName := named.Obj().Name()
+		typeName := named.Origin().Obj()
 
 		// First, check if this exact type name is marked as immutable
 		_, exists := immutableTypes[typeName]
@@ -846,12 +1127,11 @@ func isImmutableType(typ types.Type, immutableTypes map[string]immutableInfo) bo
 			return true
 		}
 
-		// for type aliases, the underlying type will be the aliased type
-		// we need to check if the underlying type is also a named type that's immutable
-		underlying := named.Underlying()
+		// immutability follows declared identity only, so a distinct named type
+		// that merely shares an immutable type's underlying structure is mutable
 
 		// check if the underlying type is a struct with embedded immutable fields
-		if structType, ok := underlying.(*types.Struct); ok {
+		if structType, ok := named.Underlying().(*types.Struct); ok {
 			for i := 0; i < structType.NumFields(); i++ {
 				field := structType.Field(i)
 				if field.Embedded() {
@@ -862,38 +1142,6 @@ func isImmutableType(typ types.Type, immutableTypes map[string]immutableInfo) bo
 				}
 			}
 		}
-
-		// Additionally, check all types in immutableTypes to see if any match this underlying structure
-		// This handles type aliases: type Alias = Immtbl
-		for immutableTypeName := range immutableTypes {
-			// for each immutable type, check if it has the same underlying structure
-			// we do this by checking if the package and type structure match
-			if named.Obj().Pkg() != nil {
-				// try to find the immutable type in the same package scope
-				pkgScope := named.Obj().Pkg().Scope()
-				if immutableObj := pkgScope.Lookup(immutableTypeName); immutableObj != nil {
-					if immutableTypeObj, ok := immutableObj.(*types.TypeName); ok {
-						immutableType := immutableTypeObj.Type()
-
-						// Handle both *types.Named and *types.Alias
-						var immutableUnderlying types.Type
-						if immutableNamed, ok := immutableType.(*types.Named); ok {
-							immutableUnderlying = immutableNamed.Underlying()
-						} else if alias, ok := immutableType.(interface{ Rhs() types.Type }); ok {
-							// For type aliases, get the RHS (underlying type)
-							immutableUnderlying = alias.Rhs()
-						} else {
-							continue
-						}
-
-						// check if the underlying types are identical
-						if types.Identical(underlying, immutableUnderlying) {
-							return true
-						}
-					}
-				}
-			}
-		}
 	}
 
 	return false
@@ -2,6 +2,7 @@ package immutablecheck
 
 import (
 	"fmt"
+	"go/types"
 	"os"
 	"strings"
 	"sync"
@@ -30,8 +31,46 @@ var (
 	logFile           *os.File
 	logMutex          sync.Mutex
 	logDestinationSet bool
+	logThreshold      logLevel = dbug
 )
 
+// verbosity orders log levels from least to most chatty
+func verbosity(level logLevel) int {
+	switch level {
+	case errr:
+		return 0
+	case warn:
+		return 1
+	case info:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// SetLogLevel sets the most verbose level that is still logged:
+// one of error, warn, info or debug
+func SetLogLevel(level string) error {
+	var lvl logLevel
+	switch level {
+	case "error":
+		lvl = errr
+	case "warn":
+		lvl = warn
+	case "info":
+		lvl = info
+	case "debug":
+		lvl = dbug
+	default:
+		return fmt.Errorf("invalid log level %q (want error, warn, info or debug)", level)
+	}
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	logThreshold = lvl
+	return nil
+}
+
 func SetLogDestination(dest string) {
 	logMutex.Lock()
 	defer logMutex.Unlock()
@@ -66,7 +105,7 @@ func putLog(level logLevel, s string) {
 
 	where := logLoc
 
-	if where == nowhere {
+	if where == nowhere || verbosity(level) > verbosity(logThreshold) {
 		return
 	}
 
@@ -106,11 +145,11 @@ func putLog(level logLevel, s string) {
 }
 
 // format is json like
-func Pretty_print_immutables(immutables *map[string]immutableInfo) string {
+func Pretty_print_immutables(immutables *map[*types.TypeName]immutableInfo) string {
 	var sb strings.Builder
 	sb.WriteString("Immutable Types Detected:\n{\n")
-	for typeName, info := range *immutables {
-		sb.WriteString(fmt.Sprintf("  \"%s\": { \"pos\": %d }\n", typeName, info.pos))
+	for _, info := range *immutables {
+		sb.WriteString(fmt.Sprintf("  \"%s\": { \"pos\": %d }\n", info.typeName, info.pos))
 	}
 	sb.WriteString("}\n")
 	return sb.String()
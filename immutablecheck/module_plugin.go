@@ -7,18 +7,38 @@ import (
 
 // pluginModule implements the module plugin interface for golangci-lint v2
 type pluginModule struct {
-	settings any // reserved for future settings
+	settings Settings
 }
 
 // PluginNew is registered with golangci-lint module plugin system.
 // It returns a linter plugin instance that exposes our analyzers.
+// settings holds linters.settings.custom.immutablecheck.settings from .golangci.yml.
 func PluginNew(settings any) (register.LinterPlugin, error) {
-	return &pluginModule{settings: settings}, nil
+	s, err := decodeSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginModule{settings: s}, nil
 }
 
-// BuildAnalyzers returns the list of analyzers provided by this plugin.
+// decodeSettings decodes and validates raw plugin settings, unset options
+// keep their defaults
+func decodeSettings(raw any) (Settings, error) {
+	s, err := register.DecodeSettings[Settings](raw)
+	if err != nil {
+		return Settings{}, err
+	}
+	if err := s.validate(); err != nil {
+		return Settings{}, err
+	}
+	return s.withDefaults(), nil
+}
+
+// BuildAnalyzers returns the list of analyzers provided by this plugin, the
+// checker and its annotation analyzer, bound to the settings the plugin was
+// created with.
 func (p *pluginModule) BuildAnalyzers() ([]*analysis.Analyzer, error) {
-	return []*analysis.Analyzer{Analyzer}, nil
+	return NewAnalyzers(p.settings), nil
 }
 
 // GetLoadMode specifies which loading mode is required by this plugin.
@@ -0,0 +1,140 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// receiverIndex denotes the receiver in a mutator table entry
+const receiverIndex = -1
+
+// stdlibMutators lists standard library functions and methods, by
+// types.Func.FullName, together with the indices of the parameters they
+// write through. Variadic parameters are listed once and cover every
+// argument passed to them.
+var stdlibMutators = map[string][]int{
+	// sort
+	"sort.Ints":        {0},
+	"sort.Float64s":    {0},
+	"sort.Strings":     {0},
+	"sort.Sort":        {0},
+	"sort.Stable":      {0},
+	"sort.Slice":       {0},
+	"sort.SliceStable": {0},
+
+	// slices: in-place reordering, and removals and insertions that shift
+	// elements within the shared backing array
+	"slices.Sort":           {0},
+	"slices.SortFunc":       {0},
+	"slices.SortStableFunc": {0},
+	"slices.Reverse":        {0},
+	"slices.Delete":         {0},
+	"slices.DeleteFunc":     {0},
+	"slices.Compact":        {0},
+	"slices.CompactFunc":    {0},
+	"slices.Insert":         {0},
+	"slices.Replace":        {0},
+
+	// maps
+	"maps.Copy":       {0},
+	"maps.DeleteFunc": {0},
+
+	// encoding
+	"encoding/json.Unmarshal":               {1},
+	"(*encoding/json.Decoder).Decode":       {0},
+	"encoding/xml.Unmarshal":                {1},
+	"(*encoding/xml.Decoder).Decode":        {0},
+	"(*encoding/xml.Decoder).DecodeElement": {0},
+	"(*encoding/gob.Decoder).Decode":        {0},
+	"encoding/binary.Read":                  {2},
+	"encoding/binary.Decode":                {2},
+	"encoding/hex.Decode":                   {0},
+	"encoding/hex.Encode":                   {0},
+	"(*encoding/base64.Encoding).Decode":    {0},
+	"(*encoding/base64.Encoding).Encode":    {0},
+	"(*encoding/base32.Encoding).Decode":    {0},
+	"(*encoding/base32.Encoding).Encode":    {0},
+
+	// fmt scanning
+	"fmt.Scan":    {0},
+	"fmt.Scanln":  {0},
+	"fmt.Scanf":   {1},
+	"fmt.Sscan":   {1},
+	"fmt.Sscanln": {1},
+	"fmt.Sscanf":  {2},
+	"fmt.Fscan":   {1},
+	"fmt.Fscanln": {1},
+	"fmt.Fscanf":  {2},
+
+	// io and readers filling caller-provided buffers
+	"io.ReadFull":                  {1},
+	"io.ReadAtLeast":               {1},
+	"io.CopyBuffer":                {2},
+	"(*os.File).Read":              {0},
+	"(*os.File).ReadAt":            {0},
+	"(*bufio.Reader).Read":         {0},
+	"(*bytes.Reader).Read":         {0},
+	"(*bytes.Reader).ReadAt":       {0},
+	"(*strings.Reader).Read":       {0},
+	"(*strings.Reader).ReadAt":     {0},
+	"crypto/rand.Read":             {0},
+	"(*math/rand.Rand).Read":       {0},
+	"(*math/rand/v2.ChaCha8).Read": {0},
+}
+
+// knownMutators merges the standard library table with the user-configured
+// entries of Settings.Mutators, which take precedence
+func knownMutators(configured map[string][]int) map[string]*mutatesFact {
+	known := make(map[string]*mutatesFact, len(stdlibMutators)+len(configured))
+	for name, params := range stdlibMutators {
+		known[name] = newMutatorSummary(params)
+	}
+	for name, params := range configured {
+		known[name] = newMutatorSummary(params)
+	}
+	return known
+}
+
+func newMutatorSummary(indices []int) *mutatesFact {
+	summary := &mutatesFact{}
+	for _, i := range indices {
+		if i == receiverIndex {
+			summary.Receiver = true
+		} else if !summary.mutatesParam(i) {
+			summary.Params = append(summary.Params, i)
+		}
+	}
+	sort.Ints(summary.Params)
+	return summary
+}
+
+// validateMutator reports a malformed Settings.Mutators entry
+func validateMutator(name string, indices []int) error {
+	if pkgPath, funcName := splitQualifiedName(strings.TrimPrefix(name, "(")); pkgPath == "" || funcName == "" {
+		return fmt.Errorf("immutablecheck: mutator %q is not fully qualified (want path/to/pkg.Func or (*path/to/pkg.Type).Method)", name)
+	}
+	if len(indices) == 0 {
+		return fmt.Errorf("immutablecheck: mutator %q lists no mutated parameters", name)
+	}
+	for _, i := range indices {
+		if i < receiverIndex {
+			return fmt.Errorf("immutablecheck: mutator %q has invalid parameter index %d (want -1 for the receiver or a parameter index)", name, i)
+		}
+	}
+	return nil
+}
+
+// parseMutatorIndices parses the colon-separated indices of a -mutators flag entry
+func parseMutatorIndices(s string) ([]int, error) {
+	var indices []int
+	for _, field := range strings.Split(s, ":") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter index %q", field)
+		}
+		indices = append(indices, i)
+	}
+	return indices, nil
+}
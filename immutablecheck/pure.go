@@ -0,0 +1,237 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// pureFact is attached to every function and method annotated @pure
+type pureFact struct{}
+
+func (*pureFact) AFact() {}
+
+func (*pureFact) String() string { return "pure" }
+
+// purePackages declare package-level functions without side effects beyond
+// writing through the arguments they are given, which checkPureCall checks
+// like any other write: slices.Sort(local) is pure, slices.Sort(global) is not
+var purePackages = map[string]bool{
+	"cmp":           true,
+	"maps":          true,
+	"math":          true,
+	"math/bits":     true,
+	"math/cmplx":    true,
+	"slices":        true,
+	"strconv":       true,
+	"strings":       true,
+	"unicode":       true,
+	"unicode/utf16": true,
+	"unicode/utf8":  true,
+}
+
+// pureFunctions lists further standard library functions without side
+// effects, by types.Func.FullName
+var pureFunctions = map[string]bool{
+	"errors.Is":     true,
+	"errors.Join":   true,
+	"errors.New":    true,
+	"errors.Unwrap": true,
+	"fmt.Errorf":    true,
+	"fmt.Sprint":    true,
+	"fmt.Sprintf":   true,
+	"fmt.Sprintln":  true,
+}
+
+// collectPureFunc records decl when its doc comment holds a pure keyword. A
+// pure function may not have side effects: its parameters and receiver are
+// read-only, and checkPure reports everything else it could change.
+func (pc *passCollector) collectPureFunc(decl *ast.FuncDecl) {
+	if !hasDirective(decl.Doc, directivePure, pc.settings.PureKeywords) {
+		return
+	}
+	fn, ok := pc.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	pc.pureFuncs[decl] = true
+	pc.pass.ExportObjectFact(fn, new(pureFact))
+	if decl.Recv != nil {
+		// a pure method does not mutate its receiver either
+		pc.pass.ExportObjectFact(fn, new(readonlyMethodFact))
+	}
+	for _, list := range []*ast.FieldList{decl.Recv, decl.Type.Params} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				if obj := pc.pass.TypesInfo.Defs[name]; obj != nil {
+					pc.readonly[obj] = true
+				}
+			}
+		}
+	}
+}
+
+// isPureFunc reports whether fn is known to be pure: annotated @pure, in
+// this package or in a dependency, or listed in purePackages or
+// pureFunctions
+func isPureFunc(pass *analysis.Pass, fn *types.Func) bool {
+	fn = fn.Origin()
+	if pass.ImportObjectFact(fn, new(pureFact)) {
+		return true
+	}
+	if pureFunctions[fn.FullName()] {
+		return true
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Recv() == nil && fn.Pkg() != nil && purePackages[fn.Pkg().Path()]
+}
+
+// checkPure reports the side effects of node inside the body of a @pure
+// function: writes to package-level variables, channel sends and closes,
+// printing, and calls of functions not known to be pure. Writes through
+// its read-only parameters and receiver are reported by the mutation checks.
+func checkPure(ctx *analysisCtx, node ast.Node) {
+	if !ctx.pure {
+		return
+	}
+
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range n.Lhs {
+			checkPureWrite(ctx, n.Pos(), lhs)
+		}
+	case *ast.IncDecStmt:
+		checkPureWrite(ctx, n.Pos(), n.X)
+	case *ast.RangeStmt:
+		if n.Tok == token.ASSIGN {
+			for _, expr := range []ast.Expr{n.Key, n.Value} {
+				if expr != nil {
+					checkPureWrite(ctx, n.Pos(), expr)
+				}
+			}
+		}
+	case *ast.SendStmt:
+		reportMutation(ctx, n.Pos(), getExpressionString(n.Chan), n.Chan, rulePure, "sending on a channel from a @pure function")
+	case *ast.CallExpr:
+		checkPureCall(ctx, n)
+	}
+}
+
+// checkPureWrite reports a write to expr when it lands in a package-level
+// variable or in storage reachable from one
+func checkPureWrite(ctx *analysisCtx, pos token.Pos, expr ast.Expr) {
+	if v := globalRoot(ctx.pass, expr); v != nil {
+		reportMutation(ctx, pos, getExpressionString(expr), expr, rulePure,
+			fmt.Sprintf("writing package-level variable %s from a @pure function", v.Name()))
+	}
+}
+
+// globalRoot returns the package-level variable at the root of a selector,
+// index, slice or dereference chain: g, g.X, pkg.G[k], (*g).X
+func globalRoot(pass *analysis.Pass, expr ast.Expr) *types.Var {
+	for {
+		switch e := stripParens(expr).(type) {
+		case *ast.Ident:
+			if isPackageLevel(pass, e) {
+				return pass.TypesInfo.ObjectOf(e).(*types.Var)
+			}
+			return nil
+		case *ast.SelectorExpr:
+			if _, ok := pass.TypesInfo.Selections[e]; !ok {
+				expr = e.Sel // pkg.G
+				continue
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.SliceExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// checkPureCall reports a call with side effects: builtins that write to
+// the world, writes to package-level variables through mutating builtins
+// and functions, and calls of functions, methods and function values not
+// known to be pure.
+// Calls already reported as mutating a read-only argument or receiver are
+// left to those checks.
+func checkPureCall(ctx *analysisCtx, call *ast.CallExpr) {
+	if tv, ok := ctx.pass.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+		return // conversion
+	}
+	if _, ok := stripParens(call.Fun).(*ast.FuncLit); ok {
+		return // func() { ... }() is checked in place
+	}
+	if ident, ok := stripParens(call.Fun).(*ast.Ident); ok {
+		if builtin, ok := ctx.pass.TypesInfo.Uses[ident].(*types.Builtin); ok {
+			switch builtin.Name() {
+			case "close", "print", "println":
+				reportMutation(ctx, call.Pos(), builtin.Name(), call.Fun, rulePure,
+					fmt.Sprintf("calling %s from a @pure function", builtin.Name()))
+			default:
+				if dst := mutatingBuiltinTarget(ctx.pass, call); dst != nil {
+					checkPureWrite(ctx, call.Pos(), dst)
+				}
+			}
+			return
+		}
+	}
+
+	// writes through the arguments, slices.Sort(g), are side effects even
+	// of calls to pure functions
+	wrote := false
+	forEachMutatedArg(ctx.pass, ctx.summaries, call, func(arg ast.Expr, _ bool, _ *types.Func, _ string) {
+		if unary, ok := stripParens(arg).(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			arg = unary.X
+		}
+		if !wrote && globalRoot(ctx.pass, arg) != nil {
+			checkPureWrite(ctx, call.Pos(), arg)
+			wrote = true
+		}
+	})
+	if wrote {
+		return
+	}
+
+	fn := typeutil.StaticCallee(ctx.pass.TypesInfo, call)
+	if fn != nil && isPureFunc(ctx.pass, fn) {
+		return
+	}
+	if isReportedMutatingCall(ctx, call) {
+		return
+	}
+	name := getExpressionString(call.Fun)
+	if fn != nil {
+		name = funcDisplayName(fn)
+	}
+	reportMutation(ctx, call.Pos(), getExpressionString(call.Fun), call.Fun, rulePure,
+		fmt.Sprintf("calling %s, which is not known to be pure, from a @pure function", name))
+}
+
+// isReportedMutatingCall reports whether the mutation checks already report
+// call: a call of a method that is not @readonly on a read-only receiver, or
+// of a function that mutates a read-only or immutable argument
+func isReportedMutatingCall(ctx *analysisCtx, call *ast.CallExpr) bool {
+	if _, fn := readonlyReceiverCall(ctx, call); fn != nil {
+		return true
+	}
+	reported := false
+	forEachMutatedArg(ctx.pass, ctx.summaries, call, func(arg ast.Expr, _ bool, _ *types.Func, _ string) {
+		if isImmutableMutationWithAliases(ctx.pass, arg, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+			reported = true
+		}
+	})
+	return reported
+}
@@ -0,0 +1,217 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// collectReadonlyParams records the parameters of fnNode annotated read-only,
+// either inline, func Render(/* @readonly */ cfg *Config), or by name in the
+// doc comment of a function declaration, // @readonly cfg. Nothing may be
+// written through a read-only parameter, in the function body or in anything
+// the body passes it to, whether or not its type is immutable. A method whose
+// doc comment holds the keyword without naming a parameter is a read-only
+// method, see markReadonlyMethod.
+func (pc *passCollector) collectReadonlyParams(fnNode ast.Node, commentGroups []*ast.CommentGroup) {
+	var doc *ast.CommentGroup
+	var lists []*ast.FieldList
+	switch fn := fnNode.(type) {
+	case *ast.FuncDecl:
+		doc = fn.Doc
+		lists = append(lists, fn.Recv, fn.Type.Params)
+	case *ast.FuncLit:
+		lists = append(lists, fn.Type.Params)
+	}
+
+	named, annotated := readonlyDocNames(doc, pc.settings.ReadonlyKeywords)
+	namesParam := false
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		start := list.Opening
+		for _, field := range list.List {
+			inline := hasCommentBetween(commentGroups, start, field.Type.Pos(), directiveReadonly, pc.settings.ReadonlyKeywords)
+			start = field.End()
+			for _, name := range field.Names {
+				namesParam = namesParam || named[name.Name]
+				if inline || named[name.Name] {
+					if obj := pc.pass.TypesInfo.Defs[name]; obj != nil {
+						pc.readonly[obj] = true
+					}
+				}
+			}
+		}
+	}
+
+	if decl, ok := fnNode.(*ast.FuncDecl); ok && decl.Recv != nil && annotated && !namesParam {
+		pc.markReadonlyMethod(decl)
+	}
+}
+
+// markReadonlyMethod records decl as a method that must not mutate its
+// receiver: the receiver is read-only in the body, and the method is
+// exported as a fact so that read-only methods, here and in importers, may
+// call it on their own receiver
+func (pc *passCollector) markReadonlyMethod(decl *ast.FuncDecl) {
+	fn, ok := pc.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	pc.pass.ExportObjectFact(fn, new(readonlyMethodFact))
+	for _, field := range decl.Recv.List {
+		for _, name := range field.Names {
+			if obj := pc.pass.TypesInfo.Defs[name]; obj != nil {
+				pc.readonly[obj] = true
+			}
+		}
+	}
+}
+
+// isReadonlyMethod reports whether fn is a method annotated @readonly, in
+// this package or in a dependency
+func isReadonlyMethod(pass *analysis.Pass, fn *types.Func) bool {
+	return pass.ImportObjectFact(fn.Origin(), new(readonlyMethodFact))
+}
+
+// checkReadonlyCall reports calls, inside a read-only method, of methods
+// that are not read-only on the method's own receiver: r.Validate() may
+// call r.String() but not r.normalize(), even when normalize does not write
+// to r today
+func checkReadonlyCall(ctx *analysisCtx, call *ast.CallExpr) {
+	recv, fn := readonlyReceiverCall(ctx, call)
+	if fn == nil {
+		return
+	}
+	reportMutation(ctx, call.Pos(), recv.Name, recv, ruleCall,
+		fmt.Sprintf("calling %s, which is not @readonly, on the receiver of a @readonly method", funcDisplayName(fn)))
+}
+
+// readonlyReceiverCall returns the receiver and the method of a call that
+// checkReadonlyCall reports, or nil. Methods known to mutate the receiver
+// are already reported by the mutating call checks, and value receivers
+// only ever see a copy.
+func readonlyReceiverCall(ctx *analysisCtx, call *ast.CallExpr) (*ast.Ident, *types.Func) {
+	sel, ok := stripParens(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	recv, ok := stripParens(sel.X).(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	obj := ctx.pass.TypesInfo.ObjectOf(recv)
+	if obj == nil || !ctx.readonly[obj] || !ctx.aliasToImmutableField[obj] || !isReceiverVar(obj) {
+		return nil, nil
+	}
+	selection, ok := ctx.pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal || throughMutableField(ctx.pass, selection) {
+		return nil, nil
+	}
+	fn, ok := selection.Obj().(*types.Func)
+	if !ok || isReadonlyMethod(ctx.pass, fn) {
+		return nil, nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil || !isPointer(sig.Recv().Type()) {
+		return nil, nil
+	}
+	if summary := lookupSummary(ctx.pass, ctx.summaries, fn); summary != nil && summary.Receiver {
+		return nil, nil
+	}
+	return recv, fn
+}
+
+// isReceiverVar reports whether obj is the receiver of a method
+func isReceiverVar(obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	return ok && v.Kind() == types.RecvVar
+}
+
+// readonlyDocNames returns the parameter names listed after a read-only
+// directive in doc, // @readonly cfg, opts, and whether doc holds one
+func readonlyDocNames(doc *ast.CommentGroup, keywords []string) (map[string]bool, bool) {
+	names := make(map[string]bool)
+	found := false
+	if doc == nil {
+		return names, false
+	}
+	for _, comment := range doc.List {
+		d, ok := commentDirective(comment, directiveReadonly, keywords)
+		if !ok {
+			continue
+		}
+		found = true
+		for _, name := range readonlyArgNames(d) {
+			names[name] = true
+		}
+	}
+	return names, found
+}
+
+// readonlyArgNames returns the names a read-only directive lists, up to the
+// first argument that is not an identifier
+func readonlyArgNames(d directive) []string {
+	var names []string
+	for _, arg := range d.args {
+		name := strings.TrimRight(arg, ",")
+		if !token.IsIdentifier(name) {
+			break
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// hasCommentBetween reports whether a directive of kind lies entirely within
+// [from, to)
+func hasCommentBetween(commentGroups []*ast.CommentGroup, from token.Pos, to token.Pos, kind string, keywords []string) bool {
+	for _, cg := range commentGroups {
+		if cg.End() <= from || cg.Pos() >= to {
+			continue
+		}
+		for _, comment := range cg.List {
+			if comment.Pos() < from || comment.End() > to {
+				continue
+			}
+			if _, ok := commentDirective(comment, kind, keywords); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readonlyParams returns the receiver and parameters of fnNode annotated
+// read-only. They start out as aliases of immutable storage, so that writes
+// through them and through everything derived from them (p := &cfg.X) are
+// reported like writes to an immutable value.
+func (pc *passCollector) readonlyParams(fnNode ast.Node) []types.Object {
+	var lists []*ast.FieldList
+	switch fn := fnNode.(type) {
+	case *ast.FuncDecl:
+		lists = append(lists, fn.Recv, fn.Type.Params)
+	case *ast.FuncLit:
+		lists = append(lists, fn.Type.Params)
+	}
+
+	var params []types.Object
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				if obj := pc.pass.TypesInfo.Defs[name]; obj != nil && pc.readonly[obj] {
+					params = append(params, obj)
+				}
+			}
+		}
+	}
+	return params
+}
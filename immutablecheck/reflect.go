@@ -0,0 +1,192 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// reflect.Value methods that return a Value sharing storage with their receiver
+var reflectDerivingMethods = map[string]bool{
+	"Elem":            true,
+	"Field":           true,
+	"FieldByIndex":    true,
+	"FieldByIndexErr": true,
+	"FieldByName":     true,
+	"FieldByNameFunc": true,
+	"Index":           true,
+	"MapIndex":        true,
+	"Slice":           true,
+	"Slice3":          true,
+	"Addr":            true,
+}
+
+// reflect.Value methods that write through their receiver
+var reflectMutatingMethods = map[string]bool{
+	"Set":          true,
+	"SetBool":      true,
+	"SetBytes":     true,
+	"SetCap":       true,
+	"SetComplex":   true,
+	"SetFloat":     true,
+	"SetInt":       true,
+	"SetIterKey":   true,
+	"SetIterValue": true,
+	"SetLen":       true,
+	"SetMapIndex":  true,
+	"SetPointer":   true,
+	"SetString":    true,
+	"SetUint":      true,
+	"SetZero":      true,
+	"Clear":        true,
+	"Grow":         true,
+}
+
+// reflect package functions whose first argument is written to
+var reflectMutatingFuncs = map[string]bool{
+	"Copy":        true,
+	"Append":      true, // writes into the shared backing array when capacity allows
+	"AppendSlice": true,
+}
+
+// trackReflectValues records variables holding a reflect.Value derived from an
+// immutable value, e.g. v := reflect.ValueOf(&im).Elem()
+func (pc *passCollector) trackReflectValues() {
+	putLog(info, "started tracking reflect values")
+
+	for _, file := range pc.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) {
+					for i, rhs := range node.Rhs {
+						pc.markReflectValue(node.Lhs[i], rhs)
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) == len(node.Values) {
+					for i, value := range node.Values {
+						pc.markReflectValue(node.Names[i], value)
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	putLog(info, "finished tracking reflect values")
+}
+
+func (pc *passCollector) markReflectValue(lhs ast.Expr, rhs ast.Expr) {
+	ident, ok := lhs.(*ast.Ident)
+	if !ok {
+		return
+	}
+	root := reflectRoot(pc.pass, rhs, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias, pc.reflectValues)
+	if root == nil {
+		return
+	}
+	if obj := pc.pass.TypesInfo.ObjectOf(ident); obj != nil {
+		pc.reflectValues[obj] = root
+	}
+}
+
+// reflectRoot returns the immutable expression a reflect.Value expression was
+// derived from (the argument of reflect.ValueOf), or nil if it is unrelated to
+// immutable values
+func reflectRoot(pass *analysis.Pass, expr ast.Expr, immutableTypes map[*types.TypeName]immutableInfo, aliasToImmutableField map[types.Object]bool, varToTypeAlias map[types.Object]*types.TypeName, reflectValues map[types.Object]ast.Expr) ast.Expr {
+	switch e := stripParens(expr).(type) {
+	case *ast.Ident:
+		if obj := pass.TypesInfo.ObjectOf(e); obj != nil {
+			return reflectValues[obj]
+		}
+
+	case *ast.CallExpr:
+		if fn := reflectFunc(pass, e); fn != nil {
+			switch fn.Name() {
+			case "ValueOf":
+				if len(e.Args) == 1 && isImmutableMutationWithAliases(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias) {
+					return e.Args[0]
+				}
+			case "Indirect":
+				if len(e.Args) == 1 {
+					return reflectRoot(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias, reflectValues)
+				}
+			}
+			return nil
+		}
+
+		sel, ok := stripParens(e.Fun).(*ast.SelectorExpr)
+		if !ok || !isReflectValueMethod(pass, sel) || !reflectDerivingMethods[sel.Sel.Name] {
+			return nil
+		}
+		return reflectRoot(pass, sel.X, immutableTypes, aliasToImmutableField, varToTypeAlias, reflectValues)
+	}
+	return nil
+}
+
+// checkReflectCall reports Set*-style method calls and reflect.Copy/Append on
+// reflect.Values derived from immutable values
+func checkReflectCall(ctx *analysisCtx, call *ast.CallExpr) {
+	var target ast.Expr
+	if fn := reflectFunc(ctx.pass, call); fn != nil {
+		if !reflectMutatingFuncs[fn.Name()] || len(call.Args) == 0 {
+			return
+		}
+		target = call.Args[0]
+	} else if sel, ok := stripParens(call.Fun).(*ast.SelectorExpr); ok && isReflectValueMethod(ctx.pass, sel) {
+		if !reflectMutatingMethods[sel.Sel.Name] {
+			return
+		}
+		target = sel.X
+	} else {
+		return
+	}
+
+	root := reflectRoot(ctx.pass, target, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias, ctx.reflectValues)
+	if root == nil {
+		return
+	}
+
+	reportMutation(ctx, call.Pos(), getExpressionString(call), root, ruleReflect, "mutating immutable value through reflect; writes via reflect.Value are checked like direct assignments")
+}
+
+// reflectFunc returns the package-level reflect function called by call, if any
+func reflectFunc(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	var ident *ast.Ident
+	switch fun := stripParens(call.Fun).(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return nil
+	}
+	fn, ok := pass.TypesInfo.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "reflect" {
+		return nil
+	}
+	if sig, ok := fn.Type().(*types.Signature); !ok || sig.Recv() != nil {
+		return nil
+	}
+	return fn
+}
+
+// isReflectValueMethod reports whether sel selects a method of reflect.Value
+func isReflectValueMethod(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	selection, ok := pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return false
+	}
+	recv := selection.Recv()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "reflect" && obj.Name() == "Value"
+}
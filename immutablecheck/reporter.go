@@ -41,6 +41,8 @@ func getExpressionString(expr ast.Expr) string {
 		return getExpressionString(e.X) + "." + e.Sel.Name
 	case *ast.IndexExpr:
 		return getExpressionString(e.X) + "[...]"
+	case *ast.SliceExpr:
+		return getExpressionString(e.X) + "[...:...]"
 	case *ast.StarExpr:
 		return "*" + getExpressionString(e.X)
 	case *ast.ParenExpr:
@@ -54,36 +56,76 @@ func getExpressionString(expr ast.Expr) string {
 	}
 }
 
-func reportMutation(pass *analysis.Pass, pos token.Pos, exprStr string, expr ast.Expr, immutableTypes map[string]immutableInfo, helpMsg string) {
-	typeName := getImmutableTypeName(pass, expr, immutableTypes)
-
-	position := pass.Fset.Position(pos)
-	sourceLine := getSourceLine(position.Filename, position.Line)
-
-	if typeName == "" {
-		msg := formatError(position, exprStr, "", position, sourceLine, helpMsg)
-		pass.Reportf(pos, "%s", msg)
+// reportMutation reports a mutation under rule, honouring the severity
+// configured for that rule
+func reportMutation(ctx *analysisCtx, pos token.Pos, exprStr string, expr ast.Expr, rule string, helpMsg string) {
+	// an @allow-mutate directive on the line of the node being checked
+	// suppresses every rule, wherever in the node the report falls, and is
+	// recorded as used for the annotation checks
+	anchor := pos
+	if ctx.node != nil {
+		anchor = ctx.node.Pos()
+	}
+	if hasAllowMutateComment(ctx.pass, anchor, ctx.commentGroups, ctx.settings.AllowMutateKeywords) {
+		ctx.suppressed[lineStart(ctx.pass.Fset, anchor)] = true
 		return
 	}
 
-	info, exists := immutableTypes[typeName]
-	if !exists {
-		msg := formatError(position, exprStr, typeName, position, sourceLine, helpMsg)
-		pass.Reportf(pos, "%s", msg)
+	sev := ctx.settings.severityOf(rule)
+	if sev == severityOff {
 		return
 	}
 
-	declPosition := pass.Fset.Position(info.pos)
+	pass := ctx.pass
+	typeName := getImmutableTypeName(pass, expr, ctx.immutableTypes)
+
+	position := pass.Fset.Position(pos)
+	declPosition := position
+	var typeLabel string
+	if typeName != nil {
+		typeLabel = qualifiedTypeName(typeName)
+		if info, exists := ctx.immutableTypes[typeName]; exists {
+			typeLabel = info.typeName
+			declPosition = pass.Fset.Position(info.pos)
+		}
+	}
+
+	var msg string
+	if ctx.settings.Format == formatCompact {
+		msg = formatCompactError(sev, exprStr, typeLabel, helpMsg)
+	} else {
+		sourceLine := getSourceLine(position.Filename, position.Line)
+		allowKeyword := ctx.settings.AllowMutateKeywords[0]
+		msg = formatError(sev, position, exprStr, typeLabel, declPosition, sourceLine, allowKeyword, helpMsg)
+	}
+
+	pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		Category: rule,
+		Message:  msg,
+	})
+}
+
+// lineStart returns the position of the start of the line holding pos
+func lineStart(fset *token.FileSet, pos token.Pos) token.Pos {
+	file := fset.File(pos)
+	return file.LineStart(file.Line(pos))
+}
 
-	msg := formatError(position, exprStr, typeName, declPosition, sourceLine, helpMsg)
-	pass.Reportf(pos, "%s", msg)
+// formatCompactError renders a diagnostic on a single line, which suits
+// -json output and tools that do not expect multi-line messages
+func formatCompactError(sev severity, exprStr string, typeName string, helpMsg string) string {
+	if typeName == "" {
+		return fmt.Sprintf("%s: cannot mutate '%s': %s", sev, exprStr, helpMsg)
+	}
+	return fmt.Sprintf("%s: cannot mutate '%s' of immutable type '%s': %s", sev, exprStr, typeName, helpMsg)
 }
 
-func formatError(mutationPos token.Position, exprStr string, typeName string, declPos token.Position, sourceLine string, helpMsg string) string {
+func formatError(sev severity, mutationPos token.Position, exprStr string, typeName string, declPos token.Position, sourceLine string, allowKeyword string, helpMsg string) string {
 	var sb strings.Builder
 
 	sb.WriteString("\n")
-	sb.WriteString("error: cannot mutate immutable type")
+	sb.WriteString(string(sev) + ": cannot mutate immutable type")
 	sb.WriteString("\n")
 
 	relPath := filepath.Base(mutationPos.Filename)
@@ -105,8 +147,12 @@ func formatError(mutationPos token.Position, exprStr string, typeName string, de
 		sb.WriteString(fmt.Sprintf("   = note: attempting to mutate '%s'\n", exprStr))
 	}
 
+	if helpMsg != "" {
+		sb.WriteString(fmt.Sprintf("   = help: %s\n", helpMsg))
+	}
+
 	// Always show the @allow-mutate suppression note
-	sb.WriteString("   = note: use //@allow-mutate comment inline to suppress this error if needed\n")
+	sb.WriteString(fmt.Sprintf("   = note: use //%s comment inline to suppress this %s if needed\n", allowKeyword, sev))
 
 	return sb.String()
 }
@@ -0,0 +1,316 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+// Settings configures the analyzer. With golangci-lint it is decoded from
+// linters.settings.custom.immutablecheck.settings in .golangci.yml.
+type Settings struct {
+	// ImmutableKeywords mark a type declaration as immutable (default: @immutable)
+	ImmutableKeywords []string `json:"immutable-keywords"`
+
+	// MutableKeywords mark a field of an immutable struct as exempt from its
+	// immutability (default: @mutable). Fields of sync and sync/atomic types
+	// are always exempt.
+	MutableKeywords []string `json:"mutable-keywords"`
+
+	// ConstructorKeywords mark a function as a constructor (default:
+	// @constructor), inside which immutable values allocated by the function
+	// itself may be initialised until they are returned or otherwise escape
+	ConstructorKeywords []string `json:"constructor-keywords"`
+
+	// ConstructorPattern is a regular expression, e.g. "^New", treating every
+	// function whose name matches it as a constructor (default: none)
+	ConstructorPattern string `json:"constructor-pattern"`
+
+	// ReadonlyKeywords mark a parameter as read-only (default: @readonly),
+	// inline before it, func Render(/* @readonly */ cfg *Config), or followed
+	// by its name in the function's doc comment, // @readonly cfg
+	ReadonlyKeywords []string `json:"readonly-keywords"`
+
+	// PureKeywords mark a function as free of side effects (default: @pure):
+	// it may not write to package-level variables or through its parameters,
+	// send on channels, or call functions not known to be pure
+	PureKeywords []string `json:"pure-keywords"`
+
+	// FrozenAfterKeywords mark a type whose values may be assembled freely
+	// until they are published (default: @frozen-after): sent on a channel,
+	// returned, stored into a package-level variable or frozen by calling the
+	// method named after the keyword (// @frozen-after Seal, default Freeze)
+	FrozenAfterKeywords []string `json:"frozen-after-keywords"`
+
+	// AllowMutateKeywords suppress a report on the same line (default: @allow-mutate)
+	AllowMutateKeywords []string `json:"allow-mutate-keywords"`
+
+	// ExemptTests skips reporting mutations inside _test.go files
+	ExemptTests bool `json:"exempt-tests"`
+
+	// ImmutableTypes lists additional immutable types by fully-qualified
+	// name, e.g. "net/url.URL" or "github.com/org/repo/domain.Config"
+	ImmutableTypes []string `json:"immutable-types"`
+
+	// Mutators extends the built-in table of standard library mutators with
+	// in-house helpers: each fully-qualified function or method name, as in
+	// "github.com/org/repo/util.Fill" or "(*github.com/org/repo/util.Buf).Reset",
+	// maps to the indices of the parameters it writes through (-1 for the
+	// receiver). Use it for functions whose source is not analyzed.
+	Mutators map[string][]int `json:"mutators"`
+
+	// CopySemantics allows mutating a private value copy of an immutable
+	// value (c := im, c := *imPtr, range values, value receivers and
+	// parameters) as long as the write stays within storage the copy holds
+	// inline. Writes through maps, slices and pointers it still shares with
+	// the original are reported.
+	CopySemantics bool `json:"copy-semantics"`
+
+	// StrictReadonly enforces const-correctness: a reference into immutable
+	// storage (&im, a *Immtbl, a @readonly parameter) may only be passed to
+	// read-only parameters and receivers, stored into @immutable fields and
+	// fields of immutable structs, or held by local variables, whose writes
+	// the alias tracking checks
+	StrictReadonly bool `json:"strict-readonly"`
+
+	// Severities maps a rule name to "error", "warning" or "off"
+	Severities map[string]string `json:"severities"`
+
+	// Engine selects the mutation checker: "ast" (default) walks the syntax
+	// tree, "ssa" inspects stores, map updates and calls in SSA form
+	Engine string `json:"engine"`
+
+	// Format selects the diagnostic layout: "pretty" (default) renders the
+	// multi-line report with source excerpt, "compact" a single line
+	Format string `json:"format"`
+}
+
+const (
+	formatPretty  = "pretty"
+	formatCompact = "compact"
+)
+
+const (
+	engineAST = "ast"
+	engineSSA = "ssa"
+)
+
+type severity string
+
+const (
+	severityError   severity = "error"
+	severityWarning severity = "warning"
+	severityOff     severity = "off"
+)
+
+// rule names, used as diagnostic categories and as keys in Settings.Severities
+const (
+	ruleReassign = "reassign"
+	ruleAssign   = "assign"
+	ruleIncDec   = "incdec"
+	ruleReflect  = "reflect"
+	ruleBuiltin  = "builtin"
+	ruleCall     = "call"
+
+	// rulePure reports side effects of @pure functions other than writes
+	// through their read-only parameters
+	rulePure = "pure"
+
+	// ruleReadonlyFlow reports, in strict mode, read-only references flowing
+	// somewhere that is not read-only
+	ruleReadonlyFlow = "readonly-flow"
+
+	// ruleUnsafePointer is opt-in: it reports every unsafe.Pointer taken from
+	// immutable storage, even when nothing is written through it
+	ruleUnsafePointer = "unsafe-pointer"
+)
+
+// ruleEngine is the category of the diagnostic saying that -engine=ssa fell
+// back to the AST engine for a package; it is not a rule and has no severity
+const ruleEngine = "engine"
+
+var knownRules = []string{
+	ruleReassign,
+	ruleAssign,
+	ruleIncDec,
+	ruleReflect,
+	ruleBuiltin,
+	ruleCall,
+	rulePure,
+	ruleReadonlyFlow,
+	ruleUnsafePointer,
+}
+
+// defaultSeverities holds the severity of rules that are not reported as
+// errors unless configured otherwise
+var defaultSeverities = map[string]severity{
+	ruleUnsafePointer: severityOff,
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		ImmutableKeywords:   []string{"@immutable"},
+		MutableKeywords:     []string{"@mutable"},
+		ConstructorKeywords: []string{"@constructor"},
+		ReadonlyKeywords:    []string{"@readonly"},
+		PureKeywords:        []string{"@pure"},
+		FrozenAfterKeywords: []string{"@frozen-after"},
+		AllowMutateKeywords: []string{"@allow-mutate"},
+		Format:              formatPretty,
+		Engine:              engineAST,
+	}
+}
+
+// withDefaults fills every unset option with its default value
+func (s Settings) withDefaults() Settings {
+	defaults := DefaultSettings()
+	if len(s.ImmutableKeywords) == 0 {
+		s.ImmutableKeywords = defaults.ImmutableKeywords
+	}
+	if len(s.MutableKeywords) == 0 {
+		s.MutableKeywords = defaults.MutableKeywords
+	}
+	if len(s.ConstructorKeywords) == 0 {
+		s.ConstructorKeywords = defaults.ConstructorKeywords
+	}
+	if len(s.ReadonlyKeywords) == 0 {
+		s.ReadonlyKeywords = defaults.ReadonlyKeywords
+	}
+	if len(s.PureKeywords) == 0 {
+		s.PureKeywords = defaults.PureKeywords
+	}
+	if len(s.FrozenAfterKeywords) == 0 {
+		s.FrozenAfterKeywords = defaults.FrozenAfterKeywords
+	}
+	if len(s.AllowMutateKeywords) == 0 {
+		s.AllowMutateKeywords = defaults.AllowMutateKeywords
+	}
+	if s.Format == "" {
+		s.Format = defaults.Format
+	}
+	if s.Engine == "" {
+		s.Engine = defaults.Engine
+	}
+	return s
+}
+
+// validate reports unknown rules, severities, formats and malformed type names
+func (s Settings) validate() error {
+	switch s.Format {
+	case "", formatPretty, formatCompact:
+	default:
+		return fmt.Errorf("immutablecheck: invalid format %q (want pretty or compact)", s.Format)
+	}
+	switch s.Engine {
+	case "", engineAST, engineSSA:
+	default:
+		return fmt.Errorf("immutablecheck: invalid engine %q (want ast or ssa)", s.Engine)
+	}
+	if _, err := regexp.Compile(s.ConstructorPattern); err != nil {
+		return fmt.Errorf("immutablecheck: invalid constructor pattern %q: %v", s.ConstructorPattern, err)
+	}
+	for rule, sev := range s.Severities {
+		if !isKnownRule(rule) {
+			return fmt.Errorf("immutablecheck: unknown rule %q in severities (known rules: %s)", rule, strings.Join(knownRules, ", "))
+		}
+		switch severity(sev) {
+		case severityError, severityWarning, severityOff:
+		default:
+			return fmt.Errorf("immutablecheck: invalid severity %q for rule %q (want error, warning or off)", sev, rule)
+		}
+	}
+	for _, name := range s.ImmutableTypes {
+		if pkgPath, typeName := splitQualifiedName(name); pkgPath == "" || typeName == "" {
+			return fmt.Errorf("immutablecheck: immutable type %q is not fully qualified (want path/to/pkg.Type)", name)
+		}
+	}
+	for name, indices := range s.Mutators {
+		if err := validateMutator(name, indices); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isKnownRule(rule string) bool {
+	for _, known := range knownRules {
+		if known == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// severityOf returns the configured severity for rule, falling back to the
+// rule's default (error for all but opt-in rules)
+func (s *Settings) severityOf(rule string) severity {
+	if sev, ok := s.Severities[rule]; ok {
+		return severity(sev)
+	}
+	if sev, ok := defaultSeverities[rule]; ok {
+		return sev
+	}
+	return severityError
+}
+
+// compileConstructorPattern compiles a validated Settings.ConstructorPattern,
+// returning nil when no pattern is configured
+func compileConstructorPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		putLog(warn, fmt.Sprintf("ignoring invalid constructor pattern %q: %v", pattern, err))
+		return nil
+	}
+	return re
+}
+
+// splitQualifiedName splits "path/to/pkg.Type" into its package path and type name
+func splitQualifiedName(name string) (string, string) {
+	idx := strings.LastIndex(name, ".")
+	if idx <= 0 {
+		return "", ""
+	}
+	return name[:idx], name[idx+1:]
+}
+
+// lookupConfiguredTypes resolves Settings.ImmutableTypes against pkg and
+// everything it transitively imports. Types from packages that are not
+// visible to pkg cannot be mutated by it and are silently skipped.
+func lookupConfiguredTypes(pkg *types.Package, names []string) []*types.TypeName {
+	if len(names) == 0 {
+		return nil
+	}
+
+	visible := make(map[string]*types.Package)
+	var visit func(p *types.Package)
+	visit = func(p *types.Package) {
+		if _, seen := visible[p.Path()]; seen {
+			return
+		}
+		visible[p.Path()] = p
+		for _, imp := range p.Imports() {
+			visit(imp)
+		}
+	}
+	visit(pkg)
+
+	var found []*types.TypeName
+	for _, name := range names {
+		pkgPath, typeName := splitQualifiedName(name)
+		p, ok := visible[pkgPath]
+		if !ok {
+			continue
+		}
+		if obj, ok := p.Scope().Lookup(typeName).(*types.TypeName); ok {
+			found = append(found, obj)
+		} else {
+			putLog(warn, fmt.Sprintf("configured immutable type %s not found", name))
+		}
+	}
+
+	return found
+}
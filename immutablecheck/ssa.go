@@ -0,0 +1,877 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// ssaWriteSite is a source-level write, keyed by the position SSA assigns to
+// the Store or MapUpdate it compiles to: the identifier, selected field name,
+// index bracket or dereference star of the written lvalue. Stores at other
+// positions (composite literal initialization, range and results plumbing)
+// are not writes the user made.
+type ssaWriteSite struct {
+	lhs  ast.Expr
+	stmt ast.Stmt
+	rule string
+}
+
+// ssaEngine reports mutations by inspecting the SSA form of the package: every
+// Store and MapUpdate whose address derives from an immutable allocation,
+// global or field, and calls of builtins and summarized functions that write
+// through such addresses. Reassignments of immutable locals that never have
+// their address taken are register moves in SSA and are not seen.
+type ssaEngine struct {
+	pc       *passCollector // for the flow states of copies, see trackFlowStates
+	ctx      *analysisCtx
+	writes   map[token.Pos]ssaWriteSite
+	calls    map[token.Pos]*ast.CallExpr // keyed by Lparen, the position of ssa.Call
+	files    map[*token.File]*ast.File
+	reported map[token.Pos]bool
+
+	// constructed holds the write targets and call operands that are
+	// values under construction where they appear, see isUnderConstruction
+	constructed map[ast.Expr]bool
+
+	// copyAssigns holds the positions of variables assigned a copy of
+	// another variable, field or pointee (c := p), which are the positions
+	// of the stores they compile to. A copy of a register holding a constant
+	// or call result is not a load in SSA, see isLocalCopy.
+	copyAssigns map[token.Pos]bool
+}
+
+// checkMutationsSSA is the SSA counterpart of checkMutations, selected with
+// -engine=ssa. It shares the collected immutable types, mutation summaries
+// and reporting with the AST engine. Packages the SSA builder cannot handle
+// are checked by the AST engine instead, with a diagnostic saying so.
+func (pc *passCollector) checkMutationsSSA() {
+	putLog(info, "started SSA mutation checking pass")
+
+	build := ssaBuildOf(pc.pass)
+	if err := build.err; err != nil {
+		putLog(warn, fmt.Sprintf("%s: %v, falling back to the AST engine", pc.pass.Pkg.Path(), err))
+		pc.reportEngineFallback(err)
+		pc.checkMutations()
+		return
+	}
+
+	ctx := &analysisCtx{
+		pass:                  pc.pass,
+		settings:              pc.settings,
+		immutableTypes:        pc.immutableTypes,
+		copiedVariables:       pc.copiedVariables,
+		aliasToImmutableField: pc.aliasToImmutableField,
+		reslices:              pc.reslices,
+		varToTypeAlias:        pc.varToTypeAlias,
+		reflectValues:         pc.reflectValues,
+		summaries:             pc.summaries,
+		ifaceValues:           pc.ifaceValues,
+		methodValues:          pc.methodValues,
+		constructing:          pc.constructing,
+		frozenTypes:           pc.frozenTypes,
+		frozenValues:          pc.frozenValues,
+		publishedVars:         pc.publishedVars,
+		immutableVars:         pc.immutableVars,
+		deepVars:              pc.deepVars,
+		varPointers:           pc.varPointers,
+		readonly:              pc.readonly,
+		suppressed:            pc.suppressed,
+	}
+	e := &ssaEngine{
+		pc:       pc,
+		ctx:      ctx,
+		writes:   make(map[token.Pos]ssaWriteSite),
+		calls:    make(map[token.Pos]*ast.CallExpr),
+		files:    make(map[*token.File]*ast.File),
+		reported: make(map[token.Pos]bool),
+
+		constructed: make(map[ast.Expr]bool),
+		copyAssigns: make(map[token.Pos]bool),
+	}
+
+	for _, file := range pc.pass.Files {
+		if pc.settings.ExemptTests && isTestFile(pc.pass, file) {
+			continue
+		}
+		e.files[pc.pass.Fset.File(file.Pos())] = file
+		e.collectSites(file)
+	}
+
+	// writes and calls found in the SSA form are reported at their
+	// statement or call, so the directive is looked for on that line
+	ctx.node = nil
+
+	for _, fn := range build.ssa.SrcFuncs {
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				e.checkInstruction(instr)
+			}
+		}
+	}
+
+	putLog(info, "finished SSA mutation checking pass")
+}
+
+// reportEngineFallback reports on the package clause of the first file that
+// the package was checked with the AST engine although -engine=ssa was asked
+// for, because its SSA form could not be built
+func (pc *passCollector) reportEngineFallback(err error) {
+	if len(pc.pass.Files) == 0 {
+		return
+	}
+	pc.pass.Report(analysis.Diagnostic{
+		Pos:      pc.pass.Files[0].Package,
+		Category: ruleEngine,
+		Message:  fmt.Sprintf("%s: %s checked with the AST engine instead of the SSA engine: %v", severityWarning, pc.pass.Pkg.Path(), err),
+	})
+}
+
+// ssaBuild is the result of the analyzer returned by newSSABuilder: the SSA
+// form buildssa.Analyzer built for the package, or the error it failed with.
+// Both are nil for packages checked with the AST engine.
+type ssaBuild struct {
+	ssa *buildssa.SSA
+	err error
+}
+
+// newSSABuilder returns the analyzer the checker bound to settings requires
+// for -engine=ssa. It runs buildssa.Analyzer only when the SSA engine is
+// selected, as settings are only known once flags are parsed, and turns a
+// builder panic on syntax it does not support yet into an error, which the
+// checker reports, rather than a crash of every analyzer of the run.
+// Requiring buildssa.Analyzer directly would build every package, the
+// dependencies the facts are computed for included, for the AST engine too.
+func newSSABuilder(settings *Settings) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "immutablessa",
+		Doc:  "build the SSA form of packages checked with immutablecheck -engine=ssa",
+		Run: func(pass *analysis.Pass) (any, error) {
+			if settings.Engine != engineSSA {
+				return &ssaBuild{}, nil
+			}
+			return runBuildSSA(pass), nil
+		},
+		Requires:   []*analysis.Analyzer{},
+		ResultType: reflect.TypeOf((*ssaBuild)(nil)),
+	}
+}
+
+// runBuildSSA runs buildssa.Analyzer on pass, recovering from a panic
+func runBuildSSA(pass *analysis.Pass) (build *ssaBuild) {
+	defer func() {
+		if r := recover(); r != nil {
+			build = &ssaBuild{err: fmt.Errorf("building SSA: %v", r)}
+		}
+	}()
+	result, err := buildssa.Analyzer.Run(pass)
+	if err != nil {
+		return &ssaBuild{err: err}
+	}
+	return &ssaBuild{ssa: result.(*buildssa.SSA)}
+}
+
+// ssaBuildOf returns the result of the SSA builder required by the analyzer
+// of pass
+func ssaBuildOf(pass *analysis.Pass) *ssaBuild {
+	for _, req := range pass.Analyzer.Requires {
+		if build, ok := pass.ResultOf[req].(*ssaBuild); ok {
+			return build
+		}
+	}
+	return &ssaBuild{err: fmt.Errorf("%s does not require the SSA builder", pass.Analyzer.Name)}
+}
+
+// collectSites records the write positions and calls of file. Variables are
+// registers rather than memory in SSA, and reflect.Value writes are opaque
+// calls, so reassignments of immutable variables (im = Immtbl{}, mi++) and
+// reflect writes are checked on the syntax tree right away, exactly as the
+// AST engine does.
+func (e *ssaEngine) collectSites(file *ast.File) {
+	add := func(lhs ast.Expr, stmt ast.Stmt, rule string) {
+		lhs = stripParens(lhs)
+		var pos token.Pos
+		switch l := lhs.(type) {
+		case *ast.SelectorExpr:
+			pos = l.Sel.Pos()
+		case *ast.IndexExpr:
+			pos = l.Lbrack
+		case *ast.StarExpr:
+			pos = l.Star
+		default:
+			return
+		}
+		site := ssaWriteSite{lhs: lhs, stmt: stmt, rule: rule}
+		e.writes[pos] = site
+		if isUnderConstruction(e.ctx, lhs) {
+			e.constructed[lhs] = true
+		}
+		if assign, ok := stmt.(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+			for i, l := range assign.Lhs {
+				if stripParens(l) == lhs {
+					e.addCompositeLitSites(assign.Rhs[i], site)
+				}
+			}
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ns, ok := e.pc.nodeStates[n]; ok {
+			e.pc.installFlowState(ns)
+		}
+		if decl, ok := n.(*ast.FuncDecl); ok {
+			e.ctx.pure = e.pc.pureFuncs[decl]
+		}
+		e.ctx.node = n
+		e.ctx.commentGroups = file.Comments
+		checkPure(e.ctx, n)
+		checkReadonlyFlow(e.ctx, n)
+		switch n.(type) {
+		case *ast.AssignStmt, *ast.IncDecStmt, *ast.CallExpr, *ast.RangeStmt:
+			// published @frozen-after values are judged by their flow state,
+			// and @immutable variables are registers as often as memory
+			e.ctx.commentGroups = file.Comments
+			checkFrozen(e.ctx, n)
+			checkImmutableVars(e.ctx, n)
+		}
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) == len(node.Rhs) {
+				for i, lhs := range node.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && e.pc.isCopyAssignment(ident, node.Rhs[i]) {
+						e.copyAssigns[ident.Pos()] = true
+					}
+				}
+			}
+			if node.Tok == token.DEFINE {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					e.ctx.commentGroups = file.Comments
+					checkReassignment(e.ctx, node, i, ident)
+					continue
+				}
+				add(lhs, node, ruleAssign)
+			}
+		case *ast.IncDecStmt:
+			if ident, ok := stripParens(node.X).(*ast.Ident); ok {
+				if isImmutableVariable(e.ctx.pass, ident, e.ctx.immutableTypes, e.ctx.varToTypeAlias) && !isUnderConstruction(e.ctx, ident) {
+					e.ctx.commentGroups = file.Comments
+					reportMutation(e.ctx, node.Pos(), ident.Name, ident, ruleIncDec, "incrementing/decrementing immutable field")
+				}
+				return true
+			}
+			add(node.X, node, ruleIncDec)
+		case *ast.CallExpr:
+			e.calls[node.Lparen] = node
+			operands := node.Args
+			if sel, ok := stripParens(node.Fun).(*ast.SelectorExpr); ok {
+				operands = append([]ast.Expr{sel.X}, operands...)
+			}
+			for _, operand := range operands {
+				if isUnderConstruction(e.ctx, operand) {
+					e.constructed[operand] = true
+				}
+			}
+			// reflect.Value writes are opaque calls in SSA, whether a method
+			// is @readonly is a property of its declaration, and the
+			// unsafe-pointer rule reports conversions, not writes
+			e.ctx.commentGroups = file.Comments
+			checkReflectCall(e.ctx, node)
+			checkReadonlyCall(e.ctx, node)
+			checkUnsafePointer(e.ctx, node)
+		}
+		return true
+	})
+}
+
+// addCompositeLitSites attributes the stores of a composite literal that SSA
+// builds in place at the written address (*p = T{...}) to the write site
+func (e *ssaEngine) addCompositeLitSites(rhs ast.Expr, site ssaWriteSite) {
+	lit, ok := stripParens(rhs).(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	e.writes[lit.Lbrace] = site
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			e.writes[kv.Colon] = site
+			e.addCompositeLitSites(kv.Value, site)
+		} else {
+			e.writes[elt.Pos()] = site
+			e.addCompositeLitSites(elt, site)
+		}
+	}
+}
+
+func (e *ssaEngine) checkInstruction(instr ssa.Instruction) {
+	switch in := instr.(type) {
+	case *ssa.Store:
+		site, ok := e.writes[in.Pos()]
+		if !ok || e.isLocalCopy(in.Addr, in) {
+			return
+		}
+		if !e.isImmutableAddr(in.Addr, nil) && !e.isDeclaredThroughAlias(site.lhs) {
+			return
+		}
+		e.reportWrite(site)
+
+	case *ssa.MapUpdate:
+		site, ok := e.writes[in.Pos()]
+		if !ok || !e.isImmutableValue(in.Map, nil) && !e.isDeclaredThroughAlias(site.lhs) {
+			return
+		}
+		e.reportWrite(site)
+
+	case ssa.CallInstruction:
+		e.checkCall(in)
+	}
+}
+
+// isDeclaredThroughAlias reports whether the variable at the root of lhs was
+// declared with an immutable alias type (var s ImmutableSlice = ...). SSA
+// values carry the aliased type only, so this is recognized on the syntax.
+func (e *ssaEngine) isDeclaredThroughAlias(lhs ast.Expr) bool {
+	for {
+		switch l := stripParens(lhs).(type) {
+		case *ast.SelectorExpr:
+			lhs = l.X
+		case *ast.IndexExpr:
+			lhs = l.X
+		case *ast.StarExpr:
+			lhs = l.X
+		case *ast.Ident:
+			typeName, ok := e.ctx.varToTypeAlias[e.ctx.pass.TypesInfo.ObjectOf(l)]
+			if !ok {
+				return false
+			}
+			_, immutable := e.ctx.immutableTypes[typeName]
+			return immutable
+		default:
+			return false
+		}
+	}
+}
+
+func (e *ssaEngine) reportWrite(site ssaWriteSite) {
+	helpMsg := "mutating immutable field in assignment"
+	switch site.rule {
+	case ruleReassign:
+		helpMsg = "reassigning whole immutable struct"
+	case ruleIncDec:
+		helpMsg = "incrementing/decrementing immutable field"
+	}
+	e.report(site.stmt.Pos(), getExpressionString(site.lhs), site.lhs, site.rule, helpMsg)
+}
+
+func (e *ssaEngine) report(pos token.Pos, exprStr string, expr ast.Expr, rule string, helpMsg string) {
+	if e.reported[pos] || e.constructed[expr] {
+		return
+	}
+	file, ok := e.files[e.ctx.pass.Fset.File(pos)]
+	if !ok {
+		return // exempt test file
+	}
+	e.reported[pos] = true
+	e.ctx.commentGroups = file.Comments
+	reportMutation(e.ctx, pos, exprStr, expr, rule, helpMsg)
+}
+
+// checkCall reports builtins writing into immutable maps and slices, and
+// calls passing immutable storage to parameters the callee mutates
+func (e *ssaEngine) checkCall(instr ssa.CallInstruction) {
+	common := instr.Common()
+	call, ok := e.calls[common.Pos()]
+	if !ok {
+		return
+	}
+
+	if builtin, ok := common.Value.(*ssa.Builtin); ok {
+		if len(common.Args) == 0 {
+			return
+		}
+		if mutatingBuiltinTarget(e.ctx.pass, call) == nil && !(builtin.Name() == "append" && isResliceValue(common.Args[0], nil)) {
+			return
+		}
+		if e.isImmutableValue(common.Args[0], nil) {
+			dst := call.Args[0]
+			e.report(call.Pos(), getExpressionString(dst), dst, ruleBuiltin,
+				fmt.Sprintf("calling %s on an immutable map or slice", builtin.Name()))
+		}
+		return
+	}
+
+	fn, recv, args := e.resolveCall(common)
+	if fn == nil {
+		return
+	}
+	summary := lookupSummary(e.ctx.pass, e.ctx.summaries, fn)
+	if summary == nil {
+		return
+	}
+
+	// AST arguments line up with SSA arguments, except for the receiver of a
+	// method expression call, which is the first AST argument
+	astArgs := call.Args
+	var astRecv ast.Expr = call.Fun
+	if sel, ok := stripParens(call.Fun).(*ast.SelectorExpr); ok {
+		if selection, ok := e.ctx.pass.TypesInfo.Selections[sel]; ok {
+			switch selection.Kind() {
+			case types.MethodVal:
+				astRecv = sel.X
+			case types.MethodExpr:
+				if len(astArgs) > 0 {
+					astRecv, astArgs = astArgs[0], astArgs[1:]
+				}
+			}
+		}
+	}
+
+	if summary.Receiver && recv != nil && !e.isLocalCopy(recv, instr) && e.isImmutableValue(recv, nil) {
+		e.report(call.Pos(), getExpressionString(astRecv), astRecv, ruleCall,
+			fmt.Sprintf("calling %s on an immutable value, which mutates its receiver", funcDisplayName(fn)))
+		return
+	}
+
+	sig := fn.Type().(*types.Signature)
+	for i, arg := range args {
+		if !summary.mutatesParam(i) {
+			continue
+		}
+		vals := map[int]ssa.Value{i: arg}
+		if sig.Variadic() && i == sig.Params().Len()-1 && !call.Ellipsis.IsValid() {
+			vals = variadicElements(arg, i)
+		}
+		for j, v := range vals {
+			if j >= len(astArgs) || !canMutateThrough(e.ctx.pass, e.ctx.summaries, fn, sig.Params().At(i).Type(), e.ctx.pass.TypesInfo.TypeOf(astArgs[j])) {
+				continue
+			}
+			if !e.isLocalCopy(v, instr) && e.isImmutableValue(v, nil) {
+				e.report(call.Pos(), getExpressionString(astArgs[j]), astArgs[j], ruleCall,
+					fmt.Sprintf("passing immutable value to %s, which mutates its parameter %s", funcDisplayName(fn), sig.Params().At(i).Name()))
+				return
+			}
+		}
+	}
+}
+
+// isResliceValue reports whether v is a re-slice with room past its length
+// on some path, so that appending to it writes into the backing array it
+// shares. A full slice expression has none when its max is its high bound.
+func isResliceValue(v ssa.Value, seen map[ssa.Value]bool) bool {
+	if seen == nil {
+		seen = make(map[ssa.Value]bool)
+	}
+	if seen[v] {
+		return false
+	}
+	seen[v] = true
+
+	switch x := v.(type) {
+	case *ssa.Slice:
+		switch {
+		case x.Max == nil:
+			return true
+		case x.Max == x.High:
+			return false
+		}
+		high, ok := x.High.(*ssa.Const)
+		if !ok || high.Value == nil {
+			return true
+		}
+		max, ok := x.Max.(*ssa.Const)
+		return !ok || max.Value == nil || !constant.Compare(high.Value, token.EQL, max.Value)
+	case *ssa.Phi:
+		for _, edge := range x.Edges {
+			if isResliceValue(edge, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveCall returns the function called by common, its receiver argument
+// if it is a method, and its remaining arguments. Calls through bound method
+// values and through interfaces holding a known dynamic type are resolved to
+// the concrete method.
+func (e *ssaEngine) resolveCall(common *ssa.CallCommon) (*types.Func, ssa.Value, []ssa.Value) {
+	if common.IsInvoke() {
+		iface, ok := common.Value.(*ssa.MakeInterface)
+		if !ok {
+			return nil, nil, nil
+		}
+		obj, _, _ := types.LookupFieldOrMethod(iface.X.Type(), true, common.Method.Pkg(), common.Method.Name())
+		fn, _ := obj.(*types.Func)
+		return fn, iface.X, common.Args
+	}
+
+	// f := im.RecvMutateNum; f(): a closure binding the receiver
+	if closure, ok := common.Value.(*ssa.MakeClosure); ok {
+		if wrapper, ok := closure.Fn.(*ssa.Function); ok && len(closure.Bindings) == 1 {
+			if fn, ok := wrapper.Object().(*types.Func); ok && fn.Type().(*types.Signature).Recv() != nil {
+				return fn, closure.Bindings[0], common.Args
+			}
+		}
+	}
+
+	callee := common.StaticCallee()
+	if callee == nil {
+		return nil, nil, nil
+	}
+	fn, ok := callee.Object().(*types.Func)
+	if !ok {
+		return nil, nil, nil
+	}
+	args := common.Args
+	if fn.Type().(*types.Signature).Recv() != nil && len(args) > 0 {
+		return fn, args[0], args[1:]
+	}
+	return fn, nil, args
+}
+
+// variadicElements returns the values stored into the implicit slice SSA
+// builds for the variadic arguments of a call, keyed by argument index
+func variadicElements(arg ssa.Value, first int) map[int]ssa.Value {
+	elems := make(map[int]ssa.Value)
+	slice, ok := arg.(*ssa.Slice)
+	if !ok {
+		return elems
+	}
+	alloc, ok := slice.X.(*ssa.Alloc)
+	if !ok {
+		return elems
+	}
+	for _, ref := range *alloc.Referrers() {
+		index, ok := ref.(*ssa.IndexAddr)
+		if !ok {
+			continue
+		}
+		c, ok := index.Index.(*ssa.Const)
+		if !ok {
+			continue
+		}
+		for _, use := range *index.Referrers() {
+			if store, ok := use.(*ssa.Store); ok && store.Addr == index {
+				elems[first+int(c.Int64())] = store.Val
+			}
+		}
+	}
+	return elems
+}
+
+// isImmutableAddr reports whether addr points into immutable storage
+func (e *ssaEngine) isImmutableAddr(addr ssa.Value, seen map[ssa.Value]bool) bool {
+	if isImmutableType(addr.Type(), e.ctx.immutableTypes) {
+		return true
+	}
+
+	switch a := addr.(type) {
+	case *ssa.FieldAddr:
+		field := structField(a.X.Type(), a.Field)
+		if isMutableField(e.ctx.pass, field) {
+			return false
+		}
+		if isImmutableField(e.ctx.pass, field) {
+			return true
+		}
+		return e.isImmutableAddr(a.X, seen)
+	case *ssa.IndexAddr:
+		// &arr[i] of an array in memory, or &s[i] of a slice value
+		if _, ok := a.X.Type().Underlying().(*types.Pointer); ok {
+			return e.isImmutableAddr(a.X, seen)
+		}
+		return e.isImmutableValue(a.X, seen)
+	case *ssa.Alloc:
+		// a read-only parameter whose address is taken lives in an Alloc
+		for _, stored := range storedValues(a) {
+			if param, ok := stored.(*ssa.Parameter); ok && e.ctx.readonly[param.Object()] {
+				return true
+			}
+		}
+		return false
+	case *ssa.Global:
+		return false
+	}
+	return e.isImmutableValue(addr, seen)
+}
+
+// isImmutableValue reports whether a pointer, slice, map or interface value
+// shares storage with an immutable value
+func (e *ssaEngine) isImmutableValue(v ssa.Value, seen map[ssa.Value]bool) bool {
+	if seen == nil {
+		seen = make(map[ssa.Value]bool)
+	}
+	if seen[v] {
+		return false
+	}
+	seen[v] = true
+
+	if isImmutableType(v.Type(), e.ctx.immutableTypes) {
+		return true
+	}
+	if param, ok := v.(*ssa.Parameter); ok && e.ctx.readonly[param.Object()] {
+		return true
+	}
+	// plain values (n := im.Num) are copies and share nothing
+	if !isReferenceType(v.Type()) && !types.IsInterface(v.Type()) && !isUnsafePointerType(v.Type()) && !isUintptrType(v.Type()) {
+		return false
+	}
+
+	switch x := v.(type) {
+	case *ssa.FieldAddr, *ssa.IndexAddr:
+		return e.isImmutableAddr(x, seen)
+	case *ssa.Alloc:
+		return false
+	case *ssa.UnOp:
+		if x.Op != token.MUL {
+			return false
+		}
+		// a reference loaded from immutable storage (s := im.Arr) shares it;
+		// a reference loaded from a local variable is whatever was stored there
+		if e.isImmutableAddr(x.X, seen) {
+			return true
+		}
+		for _, stored := range storedValues(x.X) {
+			if e.isImmutableValue(stored, seen) {
+				return true
+			}
+		}
+		return false
+	case *ssa.Field:
+		field := structField(x.X.Type(), x.Field)
+		if isMutableField(e.ctx.pass, field) {
+			return false
+		}
+		if isImmutableField(e.ctx.pass, field) {
+			return true
+		}
+		return e.isImmutableValue(x.X, seen)
+	case *ssa.Index:
+		return e.isImmutableValue(x.X, seen)
+	case *ssa.Lookup:
+		return !x.CommaOk && e.isImmutableValue(x.X, seen) && isReferenceType(x.Type())
+	case *ssa.Slice:
+		if _, ok := x.X.Type().Underlying().(*types.Pointer); ok {
+			return e.isImmutableAddr(x.X, seen)
+		}
+		return e.isImmutableValue(x.X, seen)
+	case *ssa.MakeInterface:
+		return e.isImmutableValue(x.X, seen)
+	case *ssa.TypeAssert:
+		return e.isImmutableValue(x.X, seen)
+	case *ssa.Convert:
+		// only unsafe.Pointer round trips keep pointing at the same storage;
+		// explicit conversions to mutable types are an opt-out, as in the AST engine
+		if isUnsafePointerType(x.Type()) || isUnsafePointerType(x.X.Type()) {
+			return e.isImmutableValue(x.X, seen)
+		}
+		return false
+	case *ssa.BinOp:
+		// uintptr arithmetic: uintptr(unsafe.Pointer(&im)) + unsafe.Offsetof(im.Num)
+		if x.Op == token.ADD || x.Op == token.SUB {
+			return e.isImmutableValue(x.X, seen) || e.isImmutableValue(x.Y, seen)
+		}
+		return false
+	case *ssa.Call:
+		// unsafe.Add, unsafe.Slice, unsafe.SliceData, unsafe.String, unsafe.StringData
+		if builtin, ok := x.Call.Value.(*ssa.Builtin); ok && len(x.Call.Args) > 0 {
+			switch builtin.Name() {
+			case "Add", "Slice", "SliceData", "String", "StringData":
+				return e.isImmutableValue(x.Call.Args[0], seen)
+			}
+		}
+		return false
+	case *ssa.Phi:
+		for _, edge := range x.Edges {
+			if e.isImmutableValue(edge, seen) {
+				return true
+			}
+		}
+		return false
+	case *ssa.FreeVar:
+		// a variable captured by a closure: look at what its creators bind
+		return e.isImmutableFreeVar(x, seen)
+	}
+	return false
+}
+
+// isImmutableFreeVar reports whether any closure creation binds fv to
+// immutable storage
+func (e *ssaEngine) isImmutableFreeVar(fv *ssa.FreeVar, seen map[ssa.Value]bool) bool {
+	fn := fv.Parent()
+	index := -1
+	for i, v := range fn.FreeVars {
+		if v == fv {
+			index = i
+		}
+	}
+	parent := fn.Parent()
+	if index < 0 || parent == nil {
+		return false
+	}
+	for _, block := range parent.Blocks {
+		for _, instr := range block.Instrs {
+			if mc, ok := instr.(*ssa.MakeClosure); ok && mc.Fn == fn && index < len(mc.Bindings) {
+				binding := mc.Bindings[index]
+				if e.isImmutableValue(binding, seen) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// storedValues returns every value stored into the local variable at addr,
+// following variables captured by closures back to their declaration
+func storedValues(addr ssa.Value) []ssa.Value {
+	switch a := addr.(type) {
+	case *ssa.Alloc:
+		var values []ssa.Value
+		for _, ref := range *a.Referrers() {
+			if store, ok := ref.(*ssa.Store); ok && store.Addr == a {
+				values = append(values, store.Val)
+			}
+		}
+		return values
+	case *ssa.FreeVar:
+		fn := a.Parent()
+		parent := fn.Parent()
+		if parent == nil {
+			return nil
+		}
+		index := -1
+		for i, v := range fn.FreeVars {
+			if v == a {
+				index = i
+			}
+		}
+		var values []ssa.Value
+		for _, block := range parent.Blocks {
+			for _, instr := range block.Instrs {
+				if mc, ok := instr.(*ssa.MakeClosure); ok && mc.Fn == fn && index >= 0 && index < len(mc.Bindings) {
+					values = append(values, storedValues(mc.Bindings[index])...)
+				}
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func isUnsafePointerType(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.UnsafePointer
+}
+
+func isUintptrType(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Uintptr
+}
+
+// isLocalCopy reports whether addr points into a local variable that, at
+// instr, holds a copy of an immutable value taken out of a map or slice
+// (v := m["k"]; v.Num = 1) on every path, mirroring trackCopy. With copy
+// semantics any loaded value or parameter counts as a copy (c := *imPtr), as
+// does any value assigned from a variable in the source (c := p).
+func (e *ssaEngine) isLocalCopy(addr ssa.Value, instr ssa.Instruction) bool {
+	for {
+		switch a := addr.(type) {
+		case *ssa.FieldAddr:
+			addr = a.X
+			continue
+		case *ssa.IndexAddr:
+			if _, ok := a.X.Type().Underlying().(*types.Pointer); ok {
+				addr = a.X
+				continue
+			}
+			return false
+		}
+		break
+	}
+	alloc, ok := addr.(*ssa.Alloc)
+	if !ok {
+		return false
+	}
+
+	stores := reachingStores(alloc, instr)
+	if len(stores) == 0 {
+		return false
+	}
+	valueCopy := e.ctx.settings.CopySemantics &&
+		isImmutableType(alloc.Type().(*types.Pointer).Elem(), e.ctx.immutableTypes)
+	for _, store := range stores {
+		if !isElementLoad(store.Val) && !(valueCopy && (isValueLoad(store.Val) || e.copyAssigns[store.Pos()])) {
+			return false
+		}
+	}
+	return true
+}
+
+// isElementLoad reports whether v is a value loaded out of a map or slice
+func isElementLoad(v ssa.Value) bool {
+	switch x := v.(type) {
+	case *ssa.Lookup:
+		return true
+	case *ssa.Extract:
+		_, ok := x.Tuple.(*ssa.Lookup)
+		return ok
+	case *ssa.UnOp:
+		_, ok := x.X.(*ssa.IndexAddr)
+		return x.Op == token.MUL && ok
+	}
+	return false
+}
+
+// isValueLoad reports whether v is a value copied out of existing storage or
+// passed by value, rather than one built in place
+func isValueLoad(v ssa.Value) bool {
+	switch x := v.(type) {
+	case *ssa.Parameter, *ssa.Field, *ssa.Index:
+		return true
+	case *ssa.UnOp:
+		return x.Op == token.MUL
+	}
+	return isElementLoad(v)
+}
+
+// reachingStores returns the last whole-value store to alloc on each path
+// leading to instr
+func reachingStores(alloc *ssa.Alloc, instr ssa.Instruction) []*ssa.Store {
+	var stores []*ssa.Store
+	visited := make(map[*ssa.BasicBlock]bool)
+
+	var search func(b *ssa.BasicBlock, end int)
+	search = func(b *ssa.BasicBlock, end int) {
+		for i := end - 1; i >= 0; i-- {
+			if store, ok := b.Instrs[i].(*ssa.Store); ok && store.Addr == alloc {
+				stores = append(stores, store)
+				return
+			}
+		}
+		for _, pred := range b.Preds {
+			if !visited[pred] {
+				visited[pred] = true
+				search(pred, len(pred.Instrs))
+			}
+		}
+	}
+
+	block := instr.Block()
+	for i, in := range block.Instrs {
+		if in == instr {
+			search(block, i)
+			break
+		}
+	}
+	return stores
+}
@@ -0,0 +1,248 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// exportReadonlyParams exports the read-only parameters of decl, annotated
+// @readonly or belonging to a @pure function, so that strict mode in
+// importing packages may pass read-only references to them
+func (pc *passCollector) exportReadonlyParams(decl *ast.FuncDecl) {
+	fn, ok := pc.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	params := fn.Type().(*types.Signature).Params()
+	var indices []int
+	for i := 0; i < params.Len(); i++ {
+		if pc.readonly[params.At(i)] {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) > 0 {
+		pc.pass.ExportObjectFact(fn, &readonlyParamsFact{Params: indices})
+	}
+}
+
+// isReadonlyParam reports whether parameter i of fn is read-only: annotated
+// in this package or in a dependency, or unnamed and so never used
+func isReadonlyParam(ctx *analysisCtx, fn *types.Func, i int) bool {
+	fn = fn.Origin()
+	param := fn.Type().(*types.Signature).Params().At(i)
+	if param.Name() == "" || param.Name() == "_" || ctx.readonly[param] {
+		return true
+	}
+	var fact readonlyParamsFact
+	if !ctx.pass.ImportObjectFact(fn, &fact) {
+		return false
+	}
+	for _, p := range fact.Params {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadonlyReference reports whether expr is a pointer, slice or map into
+// immutable or read-only storage: &im, imPtr, im.Tags, a @readonly parameter
+// or anything derived from one. Private copies and values still under
+// construction are the caller's own.
+func isReadonlyReference(ctx *analysisCtx, expr ast.Expr) bool {
+	expr = stripParens(expr)
+	if !isReferenceType(ctx.pass.TypesInfo.TypeOf(expr)) || isCopiedValue(ctx, expr, false) || isUnderConstruction(ctx, expr) {
+		return false
+	}
+	switch expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.SliceExpr, *ast.StarExpr, *ast.UnaryExpr:
+		return isImmutableMutationWithAliases(ctx.pass, expr, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias)
+	}
+	return false
+}
+
+// checkReadonlyFlow reports, in strict mode, read-only references flowing
+// from node into somewhere that is not read-only: parameters and receivers
+// not annotated @readonly, struct fields that are neither @immutable nor part
+// of an immutable struct, and package-level variables. Local variables are
+// read-only destinations, as the alias tracking checks every write through
+// them.
+func checkReadonlyFlow(ctx *analysisCtx, node ast.Node) {
+	if !ctx.settings.StrictReadonly {
+		return
+	}
+
+	switch n := node.(type) {
+	case *ast.CallExpr:
+		checkReadonlyFlowCall(ctx, n)
+	case *ast.AssignStmt:
+		if len(n.Lhs) == len(n.Rhs) {
+			for i := range n.Rhs {
+				checkReadonlyStore(ctx, n.Lhs[i], n.Rhs[i])
+			}
+		}
+	case *ast.ValueSpec:
+		if len(n.Names) == len(n.Values) {
+			for i := range n.Values {
+				checkReadonlyStore(ctx, n.Names[i], n.Values[i])
+			}
+		}
+	case *ast.CompositeLit:
+		checkReadonlyLiteral(ctx, n)
+	}
+}
+
+// checkReadonlyFlowCall reports read-only references passed to parameters
+// and pointer receivers of a statically resolved callee that are not
+// read-only. Arguments the callee is known to mutate are already reported by
+// the mutating call checks.
+func checkReadonlyFlowCall(ctx *analysisCtx, call *ast.CallExpr) {
+	if tv, ok := ctx.pass.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+		return // conversion
+	}
+	fn := typeutil.StaticCallee(ctx.pass.TypesInfo, call)
+	if fn == nil {
+		return
+	}
+	summary := lookupSummary(ctx.pass, ctx.summaries, fn)
+	sig := fn.Type().(*types.Signature)
+
+	args := call.Args
+	if sel, ok := stripParens(call.Fun).(*ast.SelectorExpr); ok {
+		if selection, ok := ctx.pass.TypesInfo.Selections[sel]; ok {
+			switch selection.Kind() {
+			case types.MethodVal:
+				if !throughMutableField(ctx.pass, selection) {
+					checkReadonlyReceiver(ctx, call, sel.X, receiverByAddress(ctx.pass, sel.X, sig), fn, summary)
+				}
+			case types.MethodExpr:
+				// (*T).Method(recv, args...): the receiver is the first argument
+				if len(args) == 0 {
+					return
+				}
+				checkReadonlyReceiver(ctx, call, args[0], false, fn, summary)
+				args = args[1:]
+			}
+		}
+	}
+
+	params := sig.Params()
+	for i, arg := range args {
+		p := i
+		if sig.Variadic() && p >= params.Len()-1 {
+			p = params.Len() - 1
+		}
+		if p >= params.Len() || isReadonlyParam(ctx, fn, p) || !isReadonlyReference(ctx, arg) {
+			continue
+		}
+		if summary != nil && summary.mutatesParam(p) && canMutateThrough(ctx.pass, ctx.summaries, fn, params.At(p).Type(), ctx.pass.TypesInfo.TypeOf(arg)) {
+			continue
+		}
+		reportMutation(ctx, call.Pos(), getExpressionString(arg), arg, ruleReadonlyFlow,
+			fmt.Sprintf("%s passed to %s parameter %s, which is not read-only", getExpressionString(arg), funcDisplayName(fn), params.At(p).Name()))
+	}
+}
+
+// checkReadonlyReceiver reports a read-only reference passed as the pointer
+// receiver of a method that is not @readonly. byAddress is set when the
+// method receives &recv. Value receivers only ever see a copy.
+func checkReadonlyReceiver(ctx *analysisCtx, call *ast.CallExpr, recv ast.Expr, byAddress bool, fn *types.Func, summary *mutatesFact) {
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil || !isPointer(sig.Recv().Type()) || isReadonlyMethod(ctx.pass, fn) {
+		return
+	}
+	if summary != nil && summary.Receiver {
+		return
+	}
+	if _, reported := readonlyReceiverCall(ctx, call); reported != nil {
+		return
+	}
+
+	exprStr := getExpressionString(recv)
+	if byAddress {
+		if isCopiedValue(ctx, recv, true) || isUnderConstruction(ctx, recv) ||
+			!isImmutableMutationWithAliases(ctx.pass, recv, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+			return
+		}
+		exprStr = "&" + exprStr
+	} else if !isReadonlyReference(ctx, recv) {
+		return
+	}
+	reportMutation(ctx, call.Pos(), exprStr, recv, ruleReadonlyFlow,
+		fmt.Sprintf("%s passed to %s receiver %s, which is not read-only", exprStr, funcDisplayName(fn), sig.Recv().Name()))
+}
+
+// checkReadonlyStore reports `lhs = rhs` storing a read-only reference into
+// a package-level variable, or into a struct field whose writes are not
+// checked
+func checkReadonlyStore(ctx *analysisCtx, lhs ast.Expr, rhs ast.Expr) {
+	if !isReadonlyReference(ctx, rhs) {
+		return
+	}
+
+	var where string
+	switch dst := stripParens(lhs).(type) {
+	case *ast.Ident:
+		if !isPackageLevel(ctx.pass, dst) {
+			return
+		}
+		where = "package-level variable " + dst.Name
+	case *ast.SelectorExpr:
+		selection, ok := ctx.pass.TypesInfo.Selections[dst]
+		switch {
+		case !ok:
+			where = "package-level variable " + getExpressionString(dst)
+		case selection.Kind() != types.FieldVal:
+			return
+		case isImmutableMutationWithAliases(ctx.pass, dst, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias):
+			// writes through the field are checked, as is this store
+			return
+		default:
+			where = "field " + getExpressionString(dst)
+		}
+	default:
+		return
+	}
+	reportMutation(ctx, rhs.Pos(), getExpressionString(rhs), rhs, ruleReadonlyFlow,
+		fmt.Sprintf("%s stored in %s, which is not read-only", getExpressionString(rhs), where))
+}
+
+// checkReadonlyLiteral reports read-only references stored into the fields
+// of a struct literal, unless the struct is immutable or the field @immutable
+func checkReadonlyLiteral(ctx *analysisCtx, lit *ast.CompositeLit) {
+	typ := ctx.pass.TypesInfo.TypeOf(lit)
+	if typ == nil {
+		return
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+	immutable := isImmutableType(typ, ctx.immutableTypes)
+
+	for i, elt := range lit.Elts {
+		var field *types.Var
+		value := elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			field, _ = ctx.pass.TypesInfo.ObjectOf(key).(*types.Var)
+			value = kv.Value
+		} else if i < st.NumFields() {
+			field = st.Field(i)
+		}
+		if field == nil || isImmutableField(ctx.pass, field) || (immutable && !isMutableField(ctx.pass, field)) {
+			continue
+		}
+		if !isReadonlyReference(ctx, value) {
+			continue
+		}
+		reportMutation(ctx, value.Pos(), getExpressionString(value), value, ruleReadonlyFlow,
+			fmt.Sprintf("%s stored in field %s.%s, which is not read-only", getExpressionString(value), types.TypeString(typ, types.RelativeTo(ctx.pass.Pkg)), field.Name()))
+	}
+}
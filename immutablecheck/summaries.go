@@ -0,0 +1,640 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// mutatesFact summarizes which arguments of a function or method are written
+// through in a way the caller can observe. It is exported for every function
+// with a non-empty summary so call sites in other packages can be checked.
+type mutatesFact struct {
+	Receiver bool
+	Params   []int // indices of mutated parameters, sorted
+}
+
+func (*mutatesFact) AFact() {}
+
+func (f *mutatesFact) String() string {
+	var parts []string
+	if f.Receiver {
+		parts = append(parts, "receiver")
+	}
+	for _, i := range f.Params {
+		parts = append(parts, fmt.Sprintf("param %d", i))
+	}
+	return "mutates(" + strings.Join(parts, ", ") + ")"
+}
+
+func (f *mutatesFact) mutatesParam(i int) bool {
+	for _, p := range f.Params {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryTable holds the mutation summaries known to a pass: those computed
+// for functions of the package itself, and the built-in and configured
+// mutator table keyed by types.Func.FullName. Summaries of functions in
+// other packages are imported as facts on demand.
+type summaryTable struct {
+	local map[*types.Func]*mutatesFact
+	known map[string]*mutatesFact
+}
+
+func newSummaryTable(settings *Settings) *summaryTable {
+	return &summaryTable{
+		local: make(map[*types.Func]*mutatesFact),
+		known: knownMutators(settings.Mutators),
+	}
+}
+
+// summarizer computes mutatesFacts for the functions declared in one package
+type summarizer struct {
+	pass      *analysis.Pass
+	summaries *summaryTable
+	readonly  map[types.Object]bool
+	aliases   map[types.Object]*types.Var // locals sharing storage with a parameter of the function being summarized
+}
+
+// computeMutationSummaries computes a summary for every function and method
+// declared in the package, iterating to a fixed point so that functions which
+// forward a parameter to a mutating function are themselves mutating
+func (pc *passCollector) computeMutationSummaries() {
+	putLog(info, "started computing mutation summaries")
+
+	s := &summarizer{pass: pc.pass, summaries: pc.summaries, readonly: pc.readonly}
+
+	var decls []*ast.FuncDecl
+	for _, file := range pc.pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				decls = append(decls, fn)
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, decl := range decls {
+			if s.summarize(decl) {
+				changed = true
+			}
+		}
+	}
+
+	for fn, summary := range s.summaries.local {
+		pc.pass.ExportObjectFact(fn, summary)
+	}
+
+	putLog(info, "finished computing mutation summaries")
+}
+
+// summarize recomputes the summary of decl and reports whether it grew
+func (s *summarizer) summarize(decl *ast.FuncDecl) bool {
+	fn, ok := s.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+
+	// read-only parameters and receivers promise not to be mutated; writes
+	// through them are reported inside the function, not at its callers
+	mutated := make(map[*types.Var]bool)
+	mark := func(v *types.Var) {
+		if v != nil && !s.readonly[v] {
+			mutated[v] = true
+		}
+	}
+
+	s.trackParamAliases(decl.Body, sig)
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				for _, lhs := range node.Lhs {
+					mark(s.storageParam(lhs, sig))
+				}
+			}
+		case *ast.IncDecStmt:
+			mark(s.storageParam(node.X, sig))
+		case *ast.CallExpr:
+			if target := mutatingBuiltinTarget(s.pass, node); target != nil {
+				mark(s.referencedParam(target, sig))
+			}
+			if recv := interfaceReceiver(s.pass, node); recv != nil {
+				// the dynamic value may be a pointer whose method writes through it
+				mark(s.referencedParam(recv, sig))
+			}
+			forEachMutatedArg(s.pass, s.summaries, node, func(arg ast.Expr, byAddress bool, _ *types.Func, _ string) {
+				if byAddress {
+					mark(s.storageParam(arg, sig))
+				} else {
+					mark(s.referencedParam(arg, sig))
+				}
+			})
+		}
+		return true
+	})
+
+	summary := &mutatesFact{}
+	if recv := sig.Recv(); recv != nil && mutated[recv] {
+		summary.Receiver = true
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		if mutated[sig.Params().At(i)] {
+			summary.Params = append(summary.Params, i)
+		}
+	}
+	if !summary.Receiver && len(summary.Params) == 0 {
+		return false
+	}
+
+	old := s.summaries.local[fn]
+	if old != nil && old.Receiver == summary.Receiver && len(old.Params) == len(summary.Params) {
+		return false
+	}
+	s.summaries.local[fn] = summary
+	return true
+}
+
+// trackParamAliases records the locals of body that share storage with a
+// parameter of sig, t := s, q := &p.Num or row := p.Two[0], and aliases of
+// those, iterating to a fixed point
+func (s *summarizer) trackParamAliases(body *ast.BlockStmt, sig *types.Signature) {
+	s.aliases = make(map[types.Object]*types.Var)
+
+	record := func(lhs ast.Expr, param *types.Var) {
+		ident, ok := stripParens(lhs).(*ast.Ident)
+		if !ok || param == nil {
+			return
+		}
+		if obj := s.pass.TypesInfo.ObjectOf(ident); obj != nil && obj != param && s.aliases[obj] == nil {
+			s.aliases[obj] = param
+		}
+	}
+
+	for {
+		aliases := len(s.aliases)
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) && (node.Tok == token.DEFINE || node.Tok == token.ASSIGN) {
+					for i := range node.Rhs {
+						record(node.Lhs[i], s.referencedParam(node.Rhs[i], sig))
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) == len(node.Values) {
+					for i := range node.Values {
+						record(node.Names[i], s.referencedParam(node.Values[i], sig))
+					}
+				}
+			case *ast.RangeStmt:
+				// for _, row := range p.Two: each row is loaded from p
+				if node.Value != nil && holdsReference(s.pass.TypesInfo.TypeOf(node.Value)) {
+					record(node.Value, s.loadedParam(node.X, sig))
+				}
+			}
+			return true
+		})
+		if len(s.aliases) == aliases {
+			break
+		}
+	}
+}
+
+// paramVar returns the receiver or parameter of sig that ident refers to,
+// directly or through a local aliasing it
+func (s *summarizer) paramVar(ident *ast.Ident, sig *types.Signature) *types.Var {
+	v, ok := s.pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok {
+		return nil
+	}
+	if sig.Recv() == v {
+		return v
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		if sig.Params().At(i) == v {
+			return v
+		}
+	}
+	return s.aliases[v]
+}
+
+// storageParam returns the parameter whose caller-visible storage is
+// designated by expr, i.e. the parameter a write `expr = v` mutates.
+// Writing to a parameter variable itself, or to a field of a struct
+// parameter passed by value, only touches the callee's copy.
+func (s *summarizer) storageParam(expr ast.Expr, sig *types.Signature) *types.Var {
+	switch e := stripParens(expr).(type) {
+	case *ast.SelectorExpr:
+		if _, ok := s.pass.TypesInfo.Selections[e]; !ok {
+			return nil // qualified identifier
+		}
+		if selectsMutableField(s.pass, e) {
+			return nil // interior mutability is not a mutation of the parameter
+		}
+		if isPointer(s.pass.TypesInfo.TypeOf(e.X)) {
+			return s.referencedParam(e.X, sig)
+		}
+		return s.storageParam(e.X, sig)
+	case *ast.IndexExpr:
+		if _, ok := s.pass.TypesInfo.TypeOf(e.X).Underlying().(*types.Array); ok {
+			return s.storageParam(e.X, sig)
+		}
+		return s.referencedParam(e.X, sig)
+	case *ast.StarExpr:
+		return s.referencedParam(e.X, sig)
+	}
+	return nil
+}
+
+// referencedParam returns the parameter whose caller-visible storage is
+// referenced by the value of expr (a pointer, map or slice, or an interface
+// that may hold one)
+func (s *summarizer) referencedParam(expr ast.Expr, sig *types.Signature) *types.Var {
+	expr = stripParens(expr)
+
+	switch e := expr.(type) {
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return s.storageParam(e.X, sig)
+		}
+		return nil
+	case *ast.SliceExpr:
+		if _, ok := s.pass.TypesInfo.TypeOf(e.X).Underlying().(*types.Array); ok {
+			return s.storageParam(e.X, sig)
+		}
+		return s.referencedParam(e.X, sig)
+	case *ast.CallExpr:
+		if tv, ok := s.pass.TypesInfo.Types[e.Fun]; ok && tv.IsType() && len(e.Args) == 1 {
+			return s.referencedParam(e.Args[0], sig)
+		}
+		return nil
+	}
+
+	if !holdsReference(s.pass.TypesInfo.TypeOf(expr)) {
+		return nil
+	}
+
+	return s.loadedParam(expr, sig)
+}
+
+// loadedParam returns the parameter a reference loaded from expr, anywhere
+// inside a parameter (p, p.Map, p.Two[0]), points into: storage the caller
+// shares
+func (s *summarizer) loadedParam(expr ast.Expr, sig *types.Signature) *types.Var {
+	expr = stripParens(expr)
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return s.paramVar(e, sig)
+		case *ast.SelectorExpr:
+			if _, ok := s.pass.TypesInfo.Selections[e]; !ok || selectsMutableField(s.pass, e) {
+				return nil
+			}
+			expr = stripParens(e.X)
+		case *ast.IndexExpr:
+			expr = stripParens(e.X)
+		case *ast.StarExpr:
+			expr = stripParens(e.X)
+		default:
+			return nil
+		}
+	}
+}
+
+func isPointer(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	_, ok := typ.Underlying().(*types.Pointer)
+	return ok
+}
+
+// holdsReference reports whether values of typ may share storage when
+// copied: references, and interfaces, which may hold one
+func holdsReference(typ types.Type) bool {
+	return isReferenceType(typ) || (typ != nil && types.IsInterface(typ))
+}
+
+// isReferenceType reports whether values of typ share storage when copied
+func isReferenceType(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	switch typ.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Slice:
+		return true
+	}
+	return false
+}
+
+// mutatingBuiltinTarget returns the argument written by delete, clear, copy or
+// append-on-reslice, or nil if call is not such a builtin call
+func mutatingBuiltinTarget(pass *analysis.Pass, call *ast.CallExpr) ast.Expr {
+	ident, ok := stripParens(call.Fun).(*ast.Ident)
+	if !ok || len(call.Args) == 0 {
+		return nil
+	}
+	builtin, ok := pass.TypesInfo.Uses[ident].(*types.Builtin)
+	if !ok {
+		return nil
+	}
+	switch builtin.Name() {
+	case "delete", "clear", "copy":
+		return call.Args[0]
+	case "append":
+		if slice, ok := stripParens(call.Args[0]).(*ast.SliceExpr); ok && hasSpareCapacity(pass, slice) {
+			return call.Args[0]
+		}
+	}
+	return nil
+}
+
+// lookupSummary returns the summary of fn, from this package, from the
+// mutator table, or from facts exported by the package declaring it
+func lookupSummary(pass *analysis.Pass, summaries *summaryTable, fn *types.Func) *mutatesFact {
+	fn = fn.Origin()
+	if summary, ok := summaries.local[fn]; ok {
+		return summary
+	}
+	if summary, ok := summaries.known[fn.FullName()]; ok {
+		return summary
+	}
+	if fn.Pkg() == nil || fn.Pkg() == pass.Pkg {
+		return nil
+	}
+	var fact mutatesFact
+	if pass.ImportObjectFact(fn, &fact) {
+		return &fact
+	}
+	return nil
+}
+
+// forEachMutatedArg calls visit for every argument of a statically resolved
+// call (receivers of method calls and method expressions included) that the
+// callee mutates. byAddress is set when the callee receives the address of
+// arg rather than arg itself, i.e. a pointer method called on a value.
+// what names the mutated parameter for diagnostics.
+func forEachMutatedArg(pass *analysis.Pass, summaries *summaryTable, call *ast.CallExpr, visit func(arg ast.Expr, byAddress bool, fn *types.Func, what string)) {
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil {
+		return
+	}
+	summary := lookupSummary(pass, summaries, fn)
+	if summary == nil {
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+
+	args := call.Args
+	if sel, ok := stripParens(call.Fun).(*ast.SelectorExpr); ok {
+		if selection, ok := pass.TypesInfo.Selections[sel]; ok {
+			switch selection.Kind() {
+			case types.MethodVal:
+				// methods promoted through a mutable field (an embedded
+				// sync.Mutex) mutate that field only
+				if summary.Receiver && !throughMutableField(pass, selection) {
+					visit(sel.X, receiverByAddress(pass, sel.X, sig), fn, "its receiver")
+				}
+			case types.MethodExpr:
+				// (*T).Method(recv, args...): the receiver is the first argument
+				if len(args) == 0 {
+					return
+				}
+				if summary.Receiver {
+					visit(args[0], false, fn, "its receiver")
+				}
+				args = args[1:]
+			}
+		}
+	}
+
+	params := sig.Params()
+	for i, arg := range args {
+		p := i
+		if sig.Variadic() && p >= params.Len()-1 {
+			p = params.Len() - 1
+		}
+		if p < params.Len() && summary.mutatesParam(p) && canMutateThrough(pass, summaries, fn, params.At(p).Type(), pass.TypesInfo.TypeOf(arg)) {
+			visit(arg, false, fn, "its parameter "+params.At(p).Name())
+		}
+	}
+}
+
+// canMutateThrough reports whether fn, which mutates its parameter of type
+// param, can write to an argument of type arg. A summarized function writes
+// through an interface parameter with methods only by calling them, so the
+// dynamic value must hold its storage by reference and have a method of the
+// interface that mutates its receiver: passing &im to a Bumper can write to
+// im, passing im cannot. Entries of the mutator table are taken as written.
+func canMutateThrough(pass *analysis.Pass, summaries *summaryTable, fn *types.Func, param types.Type, arg types.Type) bool {
+	if _, ok := param.(*types.TypeParam); ok || arg == nil || types.IsInterface(arg) {
+		return true
+	}
+	iface, ok := param.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 {
+		return true
+	}
+	if _, ok := summaries.known[fn.Origin().FullName()]; ok {
+		return true
+	}
+	if !isReferenceType(arg) {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(arg, true, m.Pkg(), m.Name())
+		if fn, ok := obj.(*types.Func); ok {
+			if summary := lookupSummary(pass, summaries, fn); summary != nil && summary.Receiver {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interfaceReceiver returns the receiver of call when it calls a method
+// through an interface, b.Bump(), and nil otherwise
+func interfaceReceiver(pass *analysis.Pass, call *ast.CallExpr) ast.Expr {
+	sel, ok := stripParens(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	selection, ok := pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal || !types.IsInterface(selection.Recv()) {
+		return nil
+	}
+	return sel.X
+}
+
+// receiverByAddress reports whether calling a method of sig on recv passes
+// &recv implicitly (pointer receiver called on an addressable value)
+func receiverByAddress(pass *analysis.Pass, recv ast.Expr, sig *types.Signature) bool {
+	return sig.Recv() != nil && isPointer(sig.Recv().Type()) && !isPointer(pass.TypesInfo.TypeOf(recv))
+}
+
+// trackCallableValues records interface variables holding immutable values
+// (var nm NumMutator = &im) and variables bound to method values of immutable
+// receivers (f := im.RecvMutateNum), so calls through them can be checked
+func (pc *passCollector) trackCallableValues() {
+	putLog(info, "started tracking interface and method values")
+
+	record := func(lhs ast.Expr, rhs ast.Expr) {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+		obj := pc.pass.TypesInfo.ObjectOf(ident)
+		if obj == nil {
+			return
+		}
+		rhs = stripParens(rhs)
+
+		if sel, ok := rhs.(*ast.SelectorExpr); ok {
+			if selection, ok := pc.pass.TypesInfo.Selections[sel]; ok && selection.Kind() == types.MethodVal {
+				pc.methodValues[obj] = sel
+				return
+			}
+		}
+
+		if _, isIface := obj.Type().Underlying().(*types.Interface); !isIface {
+			return
+		}
+		rhsType := pc.pass.TypesInfo.TypeOf(rhs)
+		if rhsType == nil || types.IsInterface(rhsType) {
+			return
+		}
+		if isImmutableMutationWithAliases(pc.pass, rhs, pc.immutableTypes, pc.aliasToImmutableField, pc.varToTypeAlias) {
+			pc.ifaceValues[obj] = rhs
+		}
+	}
+
+	for _, file := range pc.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) {
+					for i := range node.Rhs {
+						record(node.Lhs[i], node.Rhs[i])
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) == len(node.Values) {
+					for i := range node.Values {
+						record(node.Names[i], node.Values[i])
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	putLog(info, "finished tracking interface and method values")
+}
+
+// checkMutatingCall reports call sites that pass an immutable value to a
+// parameter or receiver the callee is known to mutate, including calls
+// through method values and through interfaces holding immutable values
+func checkMutatingCall(ctx *analysisCtx, call *ast.CallExpr) {
+	report := func(arg ast.Expr, byAddress bool, fn *types.Func, what string) {
+		if isCopiedValue(ctx, arg, byAddress) || isUnderConstruction(ctx, arg) {
+			return
+		}
+		if !isImmutableMutationWithAliases(ctx.pass, arg, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+			return
+		}
+		helpMsg := fmt.Sprintf("passing immutable value to %s, which mutates %s", funcDisplayName(fn), what)
+		if what == "its receiver" {
+			helpMsg = fmt.Sprintf("calling %s on an immutable value, which mutates its receiver", funcDisplayName(fn))
+		}
+		reportMutation(ctx, call.Pos(), getExpressionString(arg), arg, ruleCall, helpMsg)
+	}
+
+	forEachMutatedArg(ctx.pass, ctx.summaries, call, report)
+
+	switch fun := stripParens(call.Fun).(type) {
+	case *ast.Ident:
+		// f() where f := im.RecvMutateNum
+		obj := ctx.pass.TypesInfo.ObjectOf(fun)
+		sel, ok := ctx.methodValues[obj]
+		if !ok {
+			return
+		}
+		fn, ok := ctx.pass.TypesInfo.Selections[sel].Obj().(*types.Func)
+		if !ok {
+			return
+		}
+		if summary := lookupSummary(ctx.pass, ctx.summaries, fn); summary != nil && summary.Receiver {
+			report(sel.X, receiverByAddress(ctx.pass, sel.X, fn.Type().(*types.Signature)), fn, "its receiver")
+		}
+
+	case *ast.SelectorExpr:
+		// nm.RecvMutateNum() where nm is an interface holding an immutable value
+		selection, ok := ctx.pass.TypesInfo.Selections[fun]
+		if !ok || selection.Kind() != types.MethodVal || !types.IsInterface(selection.Recv()) {
+			return
+		}
+		ident, ok := stripParens(fun.X).(*ast.Ident)
+		if !ok {
+			return
+		}
+		root, ok := ctx.ifaceValues[ctx.pass.TypesInfo.ObjectOf(ident)]
+		if !ok {
+			return
+		}
+		obj, _, _ := types.LookupFieldOrMethod(ctx.pass.TypesInfo.TypeOf(root), true, selection.Obj().Pkg(), fun.Sel.Name)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			return
+		}
+		if summary := lookupSummary(ctx.pass, ctx.summaries, fn); summary != nil && summary.Receiver {
+			report(root, false, fn, "its receiver")
+		}
+	}
+}
+
+// isCopiedValue reports whether arg is a value copy (or the address of one)
+// recorded by the copy tracking pass. byAddress is set when the callee
+// receives &arg. With copy semantics this extends to the parts of a copy it
+// holds inline, see isCopyWrite, but only when the callee receives their
+// address: c.Tags, c.Attrs and c passed by value reach storage the copy
+// still shares with the original.
+func isCopiedValue(ctx *analysisCtx, arg ast.Expr, byAddress bool) bool {
+	arg = stripParens(arg)
+	if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		arg, byAddress = stripParens(unary.X), true
+	}
+	if ctx.settings.CopySemantics {
+		return byAddress && isCopyWrite(ctx, arg)
+	}
+	ident, ok := arg.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj := ctx.pass.TypesInfo.ObjectOf(ident)
+	return obj != nil && ctx.copiedVariables[obj]
+}
+
+// funcDisplayName returns fn's name as written by callers: pkg.Func or (*T).Method
+func funcDisplayName(fn *types.Func) string {
+	sig := fn.Type().(*types.Signature)
+	if recv := sig.Recv(); recv != nil {
+		return "(" + types.TypeString(recv.Type(), types.RelativeTo(fn.Pkg())) + ")." + fn.Name()
+	}
+	if fn.Pkg() != nil {
+		return fn.Pkg().Name() + "." + fn.Name()
+	}
+	return fn.Name()
+}
@@ -0,0 +1,121 @@
+package immutablecheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// derivesFromImmutableStorage reports whether expr evaluates to a pointer (or
+// unsafe.Pointer, uintptr, or unsafe slice) into immutable storage. It follows
+// address-of, pointers to immutable values, variables already known to alias
+// immutable storage, type conversions, unsafe.Add/Slice/SliceData/String/StringData and uintptr
+// arithmetic, e.g. (*int)(unsafe.Add(unsafe.Pointer(&im), 8))
+func derivesFromImmutableStorage(pass *analysis.Pass, expr ast.Expr, immutableTypes map[*types.TypeName]immutableInfo, aliasToImmutableField map[types.Object]bool, varToTypeAlias map[types.Object]*types.TypeName) bool {
+	switch e := stripParens(expr).(type) {
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			// &im.Field, including fields whose own type is immutable
+			if sel, ok := stripParens(e.X).(*ast.SelectorExpr); ok && !selectsMutableField(pass, sel) && getImmutableTypeName(pass, sel, immutableTypes) != nil {
+				return true
+			}
+			return isImmutableMutationWithAliases(pass, e.X, immutableTypes, aliasToImmutableField, varToTypeAlias)
+		}
+
+	case *ast.Ident:
+		obj := pass.TypesInfo.ObjectOf(e)
+		if obj == nil {
+			return false
+		}
+		if aliasToImmutableField[obj] {
+			return true
+		}
+		// a pointer to an immutable value (im *Immtbl) points into its
+		// storage; immutable values themselves are only reached through &im,
+		// and converting them (map[string]int(imMap)) is an explicit opt-out
+		_, isPtr := obj.Type().Underlying().(*types.Pointer)
+		return isPtr && isImmutableVariable(pass, e, immutableTypes, varToTypeAlias)
+
+	case *ast.BinaryExpr:
+		// uintptr arithmetic: uintptr(unsafe.Pointer(&im)) + unsafe.Offsetof(im.Num)
+		if e.Op == token.ADD || e.Op == token.SUB {
+			return derivesFromImmutableStorage(pass, e.X, immutableTypes, aliasToImmutableField, varToTypeAlias) ||
+				derivesFromImmutableStorage(pass, e.Y, immutableTypes, aliasToImmutableField, varToTypeAlias)
+		}
+
+	case *ast.CallExpr:
+		// type conversions: (*int)(p), unsafe.Pointer(p), uintptr(p)
+		if tv, ok := pass.TypesInfo.Types[e.Fun]; ok && tv.IsType() && len(e.Args) == 1 {
+			return derivesFromImmutableStorage(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias)
+		}
+
+		if len(e.Args) == 0 {
+			return false
+		}
+		switch unsafeBuiltin(pass, e) {
+		case "Add", "Slice", "String":
+			return derivesFromImmutableStorage(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias)
+		case "SliceData", "StringData":
+			// the argument itself shares its backing array with the result
+			return isImmutableMutationWithAliases(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias) ||
+				derivesFromImmutableStorage(pass, e.Args[0], immutableTypes, aliasToImmutableField, varToTypeAlias)
+		}
+	}
+	return false
+}
+
+// unsafeBuiltin returns the name of the unsafe package builtin called by call
+// (Add, Slice, SliceData, String, StringData, ...) or "" otherwise
+func unsafeBuiltin(pass *analysis.Pass, call *ast.CallExpr) string {
+	sel, ok := stripParens(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	builtin, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Builtin)
+	if !ok {
+		return ""
+	}
+	return builtin.Name()
+}
+
+// isUnsafePointerConversion reports whether call is a conversion to unsafe.Pointer
+func isUnsafePointerConversion(pass *analysis.Pass, call *ast.CallExpr) bool {
+	tv, ok := pass.TypesInfo.Types[call.Fun]
+	if !ok || !tv.IsType() || len(call.Args) != 1 {
+		return false
+	}
+	basic, ok := tv.Type.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.UnsafePointer
+}
+
+// checkUnsafePointer implements the opt-in unsafe-pointer rule, which reports
+// every unsafe.Pointer taken from immutable storage even if nothing is written
+// through it. Only the innermost conversion of a chain is reported.
+func checkUnsafePointer(ctx *analysisCtx, call *ast.CallExpr) {
+	if ctx.settings.severityOf(ruleUnsafePointer) == severityOff {
+		return
+	}
+	if !isUnsafePointerConversion(ctx.pass, call) {
+		return
+	}
+
+	arg := call.Args[0]
+	if !derivesFromImmutableStorage(ctx.pass, arg, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+		return
+	}
+
+	nested := false
+	ast.Inspect(arg, func(n ast.Node) bool {
+		if inner, ok := n.(*ast.CallExpr); ok && isUnsafePointerConversion(ctx.pass, inner) {
+			nested = true
+		}
+		return !nested
+	})
+	if nested {
+		return
+	}
+
+	reportMutation(ctx, call.Pos(), getExpressionString(arg), arg, ruleUnsafePointer, "taking an unsafe.Pointer to immutable storage allows unchecked writes")
+}
@@ -0,0 +1,293 @@
+package immutablecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// collectImmutableVars records the variables declared by genDecl that are
+// annotated with an immutable keyword, in the doc comment of the whole var
+// block or in the doc or line comment of a single spec. Such variables are
+// single-assignment: whatever their type, the value they are declared with
+// is the only one they ever hold. With the deep argument, //pls:immutable
+// deep, the storage they refer to is frozen as well.
+func (pc *passCollector) collectImmutableVars(genDecl *ast.GenDecl) {
+	whole, wholeOK := findDirective(genDecl.Doc, directiveImmutable, pc.settings.ImmutableKeywords)
+	for _, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		d, ok := whole, wholeOK
+		if !ok {
+			d, ok = findDirective(valueSpec.Doc, directiveImmutable, pc.settings.ImmutableKeywords)
+		}
+		if !ok {
+			d, ok = findDirective(valueSpec.Comment, directiveImmutable, pc.settings.ImmutableKeywords)
+		}
+		if ok {
+			for _, name := range valueSpec.Names {
+				pc.markImmutableVar(name, d.hasArg(argDeep))
+			}
+		}
+	}
+}
+
+// collectImmutableDefine records the variables declared by a short variable
+// declaration with an immutable directive in its line comment,
+// x := compute() // @immutable
+func (pc *passCollector) collectImmutableDefine(assign *ast.AssignStmt, commentGroups []*ast.CommentGroup) {
+	if assign.Tok != token.DEFINE {
+		return
+	}
+	d, ok := lineDirective(pc.pass, assign.Pos(), commentGroups, directiveImmutable, pc.settings.ImmutableKeywords)
+	if !ok {
+		return
+	}
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && pc.pass.TypesInfo.Defs[ident] != nil {
+			pc.markImmutableVar(ident, d.hasArg(argDeep))
+		}
+	}
+}
+
+// markImmutableVar records the variable name declares, exporting a fact for
+// package-level variables so that importers may not assign them either
+func (pc *passCollector) markImmutableVar(name *ast.Ident, deep bool) {
+	v, ok := pc.pass.TypesInfo.Defs[name].(*types.Var)
+	if !ok || name.Name == "_" {
+		return
+	}
+	pc.immutableVars[v] = true
+	if deep {
+		pc.deepVars[v] = true
+	}
+	if v.Parent() == pc.pass.Pkg.Scope() {
+		pc.pass.ExportObjectFact(v, &immutableVarFact{Deep: deep})
+	}
+}
+
+// isImmutableVar reports whether obj is a variable annotated @immutable, in
+// this package or, for package-level variables, in a dependency
+func isImmutableVar(ctx *analysisCtx, obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	if !ok || v.IsField() {
+		return false
+	}
+	if ctx.immutableVars[v] {
+		return true
+	}
+	return v.Pkg() != nil && v.Pkg() != ctx.pass.Pkg && ctx.pass.ImportObjectFact(v, new(immutableVarFact))
+}
+
+// isDeepVar reports whether obj is a variable annotated @immutable deep, in
+// this package or, for package-level variables, in a dependency
+func isDeepVar(ctx *analysisCtx, obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	if !ok {
+		return false
+	}
+	if ctx.deepVars[v] {
+		return true
+	}
+	var fact immutableVarFact
+	return v.Pkg() != nil && v.Pkg() != ctx.pass.Pkg && ctx.pass.ImportObjectFact(v, &fact) && fact.Deep
+}
+
+// trackVarPointers records the variables holding a pointer into the storage
+// of an @immutable variable (p := &x, p = &x.Inner), or a reference held by
+// a deep one (s := x.Items), so that writes through them are reported like
+// writes to the variable itself
+func (pc *passCollector) trackVarPointers() {
+	if len(pc.immutableVars) == 0 {
+		return
+	}
+	ctx := &analysisCtx{pass: pc.pass, immutableVars: pc.immutableVars, deepVars: pc.deepVars, varPointers: pc.varPointers}
+	record := func(lhs ast.Expr, rhs ast.Expr) {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+		var target types.Object
+		if unary, ok := stripParens(rhs).(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			target = immutableVarTarget(ctx, unary.X, 0)
+		} else if isReferenceType(pc.pass.TypesInfo.TypeOf(rhs)) {
+			if target = immutableVarTarget(ctx, rhs, 0); target != nil && !isDeepVar(ctx, target) {
+				target = nil
+			}
+		}
+		if target != nil {
+			if obj := pc.pass.TypesInfo.ObjectOf(ident); obj != nil {
+				pc.varPointers[obj] = target
+			}
+		}
+	}
+
+	for _, file := range pc.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) {
+					for i := range node.Rhs {
+						record(node.Lhs[i], node.Rhs[i])
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) == len(node.Values) {
+					for i := range node.Values {
+						record(node.Names[i], node.Values[i])
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// immutableVarTarget returns the @immutable variable whose own storage expr
+// refers to, after derefs dereferences of expr: x, x.Inner.Count, x.Grid[0],
+// *(&x), or *p and p.Count where p points into x. Storage x merely points to
+// (the pointee of a pointer, the elements of a slice or map) is not part of
+// the variable, unless it is annotated deep: then x.Ptr.Count, x.Items[0]
+// and everything else reachable through x is.
+func immutableVarTarget(ctx *analysisCtx, expr ast.Expr, derefs int) types.Object {
+	for {
+		switch e := stripParens(expr).(type) {
+		case *ast.Ident:
+			obj := ctx.pass.TypesInfo.ObjectOf(e)
+			if obj == nil {
+				return nil
+			}
+			if isImmutableVar(ctx, obj) && (derefs == 0 || (derefs > 0 && isDeepVar(ctx, obj))) {
+				return obj
+			}
+			if target := ctx.varPointers[obj]; target != nil && (derefs == 1 || (derefs > 1 && isDeepVar(ctx, target))) {
+				return target
+			}
+			return nil
+		case *ast.SelectorExpr:
+			selection, ok := ctx.pass.TypesInfo.Selections[e]
+			if !ok {
+				// pkg.Var
+				expr = e.Sel
+				continue
+			}
+			if selection.Kind() != types.FieldVal {
+				return nil
+			}
+			if selection.Indirect() {
+				derefs++
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			switch typ := ctx.pass.TypesInfo.TypeOf(e.X).Underlying().(type) {
+			case *types.Array:
+			case *types.Pointer:
+				if _, ok := typ.Elem().Underlying().(*types.Array); !ok {
+					return nil
+				}
+				derefs++
+			case *types.Slice, *types.Map:
+				derefs++
+			default:
+				return nil
+			}
+			expr = e.X
+		case *ast.SliceExpr:
+			// s[i:j] shares the elements of s, a[i:j] points into the array a
+			if _, ok := ctx.pass.TypesInfo.TypeOf(e.X).Underlying().(*types.Array); ok {
+				derefs--
+			}
+			expr = e.X
+		case *ast.StarExpr:
+			derefs++
+			expr = e.X
+		case *ast.UnaryExpr:
+			if e.Op != token.AND {
+				return nil
+			}
+			derefs--
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// checkImmutableVars reports assignments, increments, range clauses and
+// calls in node that write to an @immutable variable after its declaration.
+// Writes already reported as mutations of an immutable type are left to
+// those checks.
+func checkImmutableVars(ctx *analysisCtx, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range n.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && n.Tok == token.DEFINE && ctx.pass.TypesInfo.Defs[ident] != nil {
+				continue // declared here
+			}
+			rule, helpMsg := ruleAssign, "writing into @immutable variable %s after its declaration"
+			if variableIdent(ctx.pass, lhs) != nil {
+				rule, helpMsg = ruleReassign, "assigning @immutable variable %s after its declaration"
+			}
+			checkImmutableVarWrite(ctx, n.Pos(), lhs, 0, rule, helpMsg)
+		}
+	case *ast.IncDecStmt:
+		checkImmutableVarWrite(ctx, n.Pos(), n.X, 0, ruleIncDec, "incrementing/decrementing @immutable variable %s")
+	case *ast.RangeStmt:
+		if n.Tok == token.ASSIGN {
+			for _, expr := range []ast.Expr{n.Key, n.Value} {
+				if expr != nil {
+					checkImmutableVarWrite(ctx, n.Pos(), expr, 0, ruleReassign, "assigning @immutable variable %s in a range clause")
+				}
+			}
+		}
+	case *ast.CallExpr:
+		forEachMutatedArg(ctx.pass, ctx.summaries, n, func(arg ast.Expr, byAddress bool, fn *types.Func, what string) {
+			// the callee writes through arg, or through &arg for a pointer
+			// method called on an addressable value
+			derefs := 1
+			if byAddress {
+				derefs = 0
+			}
+			checkImmutableVarWrite(ctx, n.Pos(), arg, derefs, ruleCall,
+				fmt.Sprintf("passing @immutable variable %%s to %s, which mutates %s", funcDisplayName(fn), what))
+		})
+	}
+}
+
+// checkImmutableVarWrite reports a write to expr, after derefs
+// dereferences, when it lands in the storage of an @immutable variable.
+// helpMsg is formatted with the name of the variable.
+func checkImmutableVarWrite(ctx *analysisCtx, pos token.Pos, expr ast.Expr, derefs int, rule string, helpMsg string) {
+	target := immutableVarTarget(ctx, expr, derefs)
+	if target == nil {
+		return
+	}
+	if ident := variableIdent(ctx.pass, expr); ident != nil && derefs == 0 {
+		// im = Immtbl{} is already a reassignment of an immutable value
+		if isImmutableVariable(ctx.pass, ident, ctx.immutableTypes, ctx.varToTypeAlias) && !isPointer(target.Type()) {
+			return
+		}
+	} else if isImmutableMutationWithAliases(ctx.pass, expr, ctx.immutableTypes, ctx.aliasToImmutableField, ctx.varToTypeAlias) {
+		return
+	}
+	reportMutation(ctx, pos, getExpressionString(expr), expr, rule, fmt.Sprintf(helpMsg, target.Name()))
+}
+
+// variableIdent returns the identifier naming the variable expr is, x or
+// pkg.X, or nil when expr is not a plain variable
+func variableIdent(pass *analysis.Pass, expr ast.Expr) *ast.Ident {
+	switch e := stripParens(expr).(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		if _, ok := pass.TypesInfo.Selections[e]; !ok {
+			return e.Sel
+		}
+	}
+	return nil
+}
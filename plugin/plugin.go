@@ -9,7 +9,7 @@ import (
 type analyzerPlugin struct{}
 
 func (analyzerPlugin) GetAnalyzers() []*analysis.Analyzer {
-	return []*analysis.Analyzer{immutablecheck.Analyzer}
+	return []*analysis.Analyzer{immutablecheck.Analyzer, immutablecheck.AnnotationAnalyzer}
 }
 
 // This variable must be named "AnalyzerPlugin" and be exported for golangci-lint
@@ -11,7 +11,28 @@ linters:
       immutablecheck:
         type: "module"
         description: Immutable type mutation checker (pls-dont-go)
-        # settings: {} # add plugin-specific settings here if needed
+        # settings:
+        #   immutable-keywords: ["@immutable"]
+        #   mutable-keywords: ["@mutable"]
+        #   constructor-keywords: ["@constructor"]
+        #   constructor-pattern: "^New"
+        #   readonly-keywords: ["@readonly"]
+        #   frozen-after-keywords: ["@frozen-after"]
+        #   pure-keywords: ["@pure"]
+        #   allow-mutate-keywords: ["@allow-mutate"]
+        #   exempt-tests: false
+        #   immutable-types: ["net/url.URL"]
+        #   engine: ast
+        #   copy-semantics: false
+        #   strict-readonly: false
+        #   mutators:
+        #     "example.com/util.Fill": [0]
+        #   severities:
+        #     reassign: error
+        #     assign: error
+        #     incdec: warning
+        #     unsafe-pointer: warning # opt-in, off by default
+        #   format: pretty
 
 EOF
 
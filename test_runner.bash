@@ -1,6 +1,6 @@
 #!/bin/bash
 
-# Usage: ./test_runner.bash [file]
+# Usage: [LINT_FLAGS=...] ./test_runner.bash [file]
 # Default to examples/all.go if no argument provided
 file="${1:-examples/all.go}"
 
@@ -23,7 +23,7 @@ NC='\033[0m' # No Color
 
   # Extract only line numbers from linter (ignore column and message), ensure sorted
   # Note: linter outputs to stderr, so we need 2>&1 to capture it
-  ./immutablelint "$file" 2>&1 | grep "$file" | cut -d: -f2 | LC_ALL=C sort | uniq > /tmp/linter_hits.txt
+  ./immutablelint $LINT_FLAGS "$file" 2>&1 | grep "$file" | cut -d: -f2 | LC_ALL=C sort | uniq > /tmp/linter_hits.txt
 
   # CAUGHT
   LC_ALL=C comm -12 /tmp/catch_lines.txt /tmp/linter_hits.txt | while read l; do